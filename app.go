@@ -5,10 +5,16 @@ import (
     "fmt"
     "github.com/wukong-cloud/wrpc-go/internal/register"
     "github.com/wukong-cloud/wrpc-go/util/logx"
+    "os"
+    "os/signal"
+    "strings"
     "sync"
+    "syscall"
     "time"
 )
 
+const defaultStartTimeout = 10 * time.Second
+
 type AppOption interface {
     apply(app *App)
 }
@@ -21,7 +27,7 @@ func (f AppOptionFunc)apply(app *App) {
 
 func WithServer(server Server) AppOption {
     return AppOptionFunc(func(app *App) {
-        app.serverMap[server.Name()] = server
+        app.AddServer(server)
     })
 }
 
@@ -31,11 +37,65 @@ func WithRegister(register register.Register) AppOption {
     })
 }
 
+// WithShutdownGrace sets how long Shutdown waits after unregistering before it stops the servers,
+// giving clients time to see the endpoints disappear from discovery.
+func WithShutdownGrace(grace time.Duration) AppOption {
+    return AppOptionFunc(func(app *App) {
+        app.shutdownGrace = grace
+    })
+}
+
+func WithShutdownTimeout(timeout time.Duration) AppOption {
+    return AppOptionFunc(func(app *App) {
+        app.shutdownTimeout = timeout
+    })
+}
+
+// WithStartTimeout bounds how long Run waits for every server to bind its listener.
+func WithStartTimeout(timeout time.Duration) AppOption {
+    return AppOptionFunc(func(app *App) {
+        app.startTimeout = timeout
+    })
+}
+
+// WithSignals replaces the signals that trigger Shutdown, no signal is handled if it is called without any.
+func WithSignals(signals ...os.Signal) AppOption {
+    return AppOptionFunc(func(app *App) {
+        app.signals = signals
+    })
+}
+
 type App struct {
     serverMap map[string]Server
+    servers []Server
     stopChan chan struct{}
+    stopOnce sync.Once
+    stopErr error
+    // failChan gets the error of a server whose Start returned one.
+    failChan chan error
     wg sync.WaitGroup
     register register.Register
+    shutdownGrace time.Duration
+    shutdownTimeout time.Duration
+    startTimeout time.Duration
+    signals []os.Signal
+}
+
+type multiError []error
+
+func (errs multiError)Error() string {
+    msgs := make([]string, 0, len(errs))
+    for _, err := range errs {
+        msgs = append(msgs, err.Error())
+    }
+    return strings.Join(msgs, "; ")
+}
+
+func (errs multiError)err() error {
+    if len(errs) == 0 {
+        return nil
+    }
+    return errs
 }
 
 func NewApp(opts ...AppOption) *App {
@@ -44,6 +104,10 @@ func NewApp(opts ...AppOption) *App {
        serverMap: make(map[string]Server),
        stopChan: make(chan struct{}),
        register: register.NewRegister(conf.RegisterConfig),
+       shutdownGrace: conf.ShutdownGrace,
+       shutdownTimeout: conf.ShutdownTimeout,
+       startTimeout: defaultStartTimeout,
+       signals: []os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT},
    }
    for _, opt := range opts {
        opt.apply(app)
@@ -52,6 +116,15 @@ func NewApp(opts ...AppOption) *App {
 }
 
 func (app *App)AddServer(server Server) {
+    if _, ok := app.serverMap[server.Name()]; !ok {
+        app.servers = append(app.servers, server)
+    } else {
+        for i := range app.servers {
+            if app.servers[i].Name() == server.Name() {
+                app.servers[i] = server
+            }
+        }
+    }
     app.serverMap[server.Name()] = server
 }
 
@@ -59,43 +132,145 @@ func (app *App)Run() error {
     if len(app.serverMap) == 0 {
         return fmt.Errorf("server not found")
     }
-    for _, server := range app.serverMap {
+    app.failChan = make(chan error, len(app.servers))
+    for _, server := range app.servers {
         app.wg.Add(1)
-        go func() {
+        go func(server Server) {
             defer app.wg.Done()
             if err := server.Start(); err != nil {
-                panic(fmt.Sprintf("start server %s failed:%+v", server.Name(), err))
-                return
+                logx.Logf("server %s exit:%v", server.Name(), err)
+                app.failChan <- fmt.Errorf("server %s exit:%v", server.Name(), err)
+            }
+        }(server)
+    }
+
+    var errs multiError
+    for _, server := range app.servers {
+        waiter, ok := server.(readyWaiter)
+        if !ok {
+            continue
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), app.startTimeout)
+        err := waiter.WaitReady(ctx)
+        cancel()
+        if err != nil {
+            errs = append(errs, fmt.Errorf("start server %s failed:%v", server.Name(), err))
+        }
+    }
+    if len(errs) > 0 {
+        // every server was started, one that is not ready yet may still come up.
+        ctx, cancel := context.WithTimeout(context.Background(), app.shutdownTimeout)
+        for _, server := range app.servers {
+            if err := server.Stop(ctx); err != nil {
+                errs = append(errs, fmt.Errorf("stop server %s failed:%v", server.Name(), err))
             }
-        }()
+        }
+        cancel()
+        return errs
     }
-    time.Sleep(time.Second)
-    for _, server := range app.serverMap {
-        app.register.Register(*server.Target())
+
+    for _, server := range app.servers {
+        if err := app.register.Register(*server.Target()); err != nil {
+            logx.Logf("register server %s failed:%v", server.Name(), err)
+        }
     }
     return app.loop()
 }
 
+// Shutdown unregisters every server, waits for the shutdown grace period and then stops
+// the servers in the order they were added. Run returns once Shutdown is done.
+func (app *App)Shutdown(ctx context.Context) error {
+    app.stopOnce.Do(func() {
+        app.stopErr = app.shutdown(ctx)
+        close(app.stopChan)
+    })
+    <- app.stopChan
+    return app.stopErr
+}
+
+func (app *App)shutdown(ctx context.Context) error {
+    logx.Log("ready to stop server")
+    for _, server := range app.servers {
+        logx.Logf("unregister server %s", server.Name())
+        if err := app.register.UnRegister(*server.Target()); err != nil {
+            logx.Logf("unregister server %s failed:%v", server.Name(), err)
+        }
+    }
+
+    if app.shutdownGrace > 0 {
+        logx.Logf("wait %s before stop server", app.shutdownGrace)
+        timer := time.NewTimer(app.shutdownGrace)
+        select {
+        case <- ctx.Done():
+        case <- timer.C:
+        }
+        timer.Stop()
+    }
+
+    var errs multiError
+    for _, server := range app.servers {
+        logx.Logf("stop server %s", server.Name())
+        if err := server.Stop(ctx); err != nil {
+            logx.Logf("stop server %s failed:%v", server.Name(), err)
+            errs = append(errs, fmt.Errorf("stop server %s failed:%v", server.Name(), err))
+        }
+    }
+
+    done := make(chan struct{})
+    go func() {
+        app.wg.Wait()
+        close(done)
+    }()
+    select {
+    case <- ctx.Done():
+        errs = append(errs, ctx.Err())
+    case <- done:
+        logx.Log("server is stopped")
+    }
+    return errs.err()
+}
+
 func (app *App)loop() error {
+    sigChan := make(chan os.Signal, 1)
+    if len(app.signals) > 0 {
+        signal.Notify(sigChan, app.signals...)
+        defer signal.Stop(sigChan)
+    }
     timer := time.NewTicker(time.Second*10)
+    defer timer.Stop()
     for {
         select {
         case <- timer.C:
             logx.Log("keep alive")
-            for _, server := range app.serverMap {
+            for _, server := range app.servers {
                 app.register.KeepAlive(*server.Target())
             }
-        case <- app.stopChan:
-            logx.Log("ready to stop server")
-            for _, server := range app.serverMap {
-                logx.Logf("stop server %s", server.Name())
-                app.register.UnRegister(*server.Target())
-                logx.Logf("stop server %s", server.Name())
-                server.Stop(context.TODO())
+        case sig := <- sigChan:
+            logx.Logf("receive signal %s", sig)
+            return app.stop()
+        case err := <- app.failChan:
+            // a server stopped serving, the others are shut down with it.
+            errs := multiError{err}
+            if stopErr := app.stop(); stopErr != nil {
+                errs = append(errs, stopErr)
             }
-            app.wg.Wait()
-            logx.Log("server is stopped")
-            return nil
+            return errs
+        case <- app.stopChan:
+            return app.stopErr
         }
     }
 }
+
+// stop runs Shutdown bounded by the shutdown timeout.
+func (app *App)stop() error {
+    ctx := context.Background()
+    var cancel context.CancelFunc
+    if app.shutdownTimeout > 0 {
+        ctx, cancel = context.WithTimeout(ctx, app.shutdownTimeout)
+    }
+    err := app.Shutdown(ctx)
+    if cancel != nil {
+        cancel()
+    }
+    return err
+}
@@ -0,0 +1,112 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/internal/register"
+)
+
+// fakeServer serves until it is stopped or fail gets an error, it has no WaitReady.
+type fakeServer struct {
+    name    string
+    fail    chan error
+    stopped chan struct{}
+}
+
+func newFakeServer(name string) *fakeServer {
+    return &fakeServer{name: name, fail: make(chan error, 1), stopped: make(chan struct{})}
+}
+
+func (s *fakeServer)Start() error {
+    select {
+    case err := <- s.fail:
+        return err
+    case <- s.stopped:
+        return nil
+    }
+}
+
+func (s *fakeServer)Stop(ctx context.Context) error {
+    select {
+    case <- s.stopped:
+    default:
+        close(s.stopped)
+    }
+    return nil
+}
+
+func (s *fakeServer)Name() string {
+    return s.name
+}
+
+func (s *fakeServer)Target() *register.Target {
+    return &register.Target{Name: s.name}
+}
+
+func TestAppStopsWhenAServerFails(t *testing.T) {
+    wrpc_go.LoadConfig(nil)
+    failing, other := newFakeServer("Failing"), newFakeServer("Other")
+    app := wrpc_go.NewApp(wrpc_go.WithServer(failing), wrpc_go.WithServer(other), wrpc_go.WithSignals(),
+        wrpc_go.WithShutdownGrace(0), wrpc_go.WithShutdownTimeout(time.Second))
+    done := make(chan error, 1)
+    go func() {
+        done <- app.Run()
+    }()
+
+    failing.fail <- errors.New("accept failed")
+    select {
+    case err := <- done:
+        if err == nil || !strings.Contains(err.Error(), "accept failed") {
+            t.Fatalf("Run returned %v, want the error of the server", err)
+        }
+    case <- time.After(2 * time.Second):
+        t.Fatal("Run did not return after a server failed")
+    }
+    select {
+    case <- other.stopped:
+    default:
+        t.Fatal("the other server was not stopped")
+    }
+}
+
+// slowServer never becomes ready.
+type slowServer struct {
+    *fakeServer
+}
+
+func (s slowServer)WaitReady(ctx context.Context) error {
+    <- ctx.Done()
+    return ctx.Err()
+}
+
+func TestAppStopsServersWhenOneIsNotReady(t *testing.T) {
+    wrpc_go.LoadConfig(nil)
+    slow, other := slowServer{newFakeServer("Slow")}, newFakeServer("Other")
+    app := wrpc_go.NewApp(wrpc_go.WithServer(slow), wrpc_go.WithServer(other), wrpc_go.WithSignals(),
+        wrpc_go.WithStartTimeout(50 * time.Millisecond), wrpc_go.WithShutdownTimeout(time.Second))
+    done := make(chan error, 1)
+    go func() {
+        done <- app.Run()
+    }()
+
+    select {
+    case err := <- done:
+        if err == nil || !strings.Contains(err.Error(), "start server Slow failed") {
+            t.Fatalf("Run returned %v, want the start error of Slow", err)
+        }
+    case <- time.After(2 * time.Second):
+        t.Fatal("Run did not return after the start timeout")
+    }
+    for _, s := range []*fakeServer{slow.fakeServer, other} {
+        select {
+        case <- s.stopped:
+        default:
+            t.Fatalf("server %s was not stopped", s.name)
+        }
+    }
+}
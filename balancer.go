@@ -0,0 +1,186 @@
+package wrpc_go
+
+import (
+    "math/rand"
+    "net/url"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+)
+
+// Endpoint is a server a Balancer can pick. The weight comes from the address, e.g.
+// "127.0.0.1:8080?weight=3", and defaults to 1.
+type Endpoint interface {
+    Addr() string
+    Weight() int
+    // Outstanding is the number of requests sent to the endpoint and not answered yet.
+    Outstanding() int64
+}
+
+// Balancer picks the endpoint of a request that is not bound to an address or a consistent
+// hash key. endpoints is never empty and Pick must return one of them, it is called concurrently.
+type Balancer interface {
+    Pick(endpoints []Endpoint) Endpoint
+    Name() string
+}
+
+type BalancerBuilder func() Balancer
+
+const (
+    BalancerRoundRobin         = "round-robin"
+    BalancerRandom             = "random"
+    BalancerWeightedRoundRobin = "weighted-round-robin"
+    BalancerLeastOutstanding   = "least-outstanding"
+    BalancerP2C                = "p2c"
+)
+
+var balancerMap map[string]BalancerBuilder
+
+func init() {
+    balancerMap = make(map[string]BalancerBuilder)
+    RegisterBalancer(BalancerRoundRobin, func() Balancer { return &roundRobinBalancer{} })
+    RegisterBalancer(BalancerRandom, func() Balancer { return &randomBalancer{} })
+    RegisterBalancer(BalancerWeightedRoundRobin, func() Balancer { return &weightedRoundRobinBalancer{} })
+    RegisterBalancer(BalancerLeastOutstanding, func() Balancer { return &leastOutstandingBalancer{} })
+    RegisterBalancer(BalancerP2C, func() Balancer { return &p2cBalancer{} })
+}
+
+// RegisterBalancer makes a balancer available by name in client-config, each client builds its own.
+func RegisterBalancer(name string, builder BalancerBuilder) {
+    if name == "" || builder == nil {
+        return
+    }
+    balancerMap[name] = builder
+}
+
+func NewBalancer(name string) Balancer {
+    if builder, ok := balancerMap[name]; ok {
+        return builder()
+    }
+    return nil
+}
+
+const defaultWeight = 1
+
+// parseEndpoint splits the weight off an address like "127.0.0.1:8080?weight=3".
+func parseEndpoint(addr string) (string, int) {
+    i := strings.IndexByte(addr, '?')
+    if i < 0 {
+        return addr, defaultWeight
+    }
+    weight := defaultWeight
+    if query, err := url.ParseQuery(addr[i+1:]); err == nil {
+        if w, err := strconv.Atoi(query.Get("weight")); err == nil && w > 0 {
+            weight = w
+        }
+    }
+    return addr[:i], weight
+}
+
+// load compares endpoints of different weights, an endpoint of weight 2 is as loaded as one
+// of weight 1 with half its outstanding requests.
+func load(ep Endpoint) float64 {
+    weight := ep.Weight()
+    if weight <= 0 {
+        weight = defaultWeight
+    }
+    return float64(ep.Outstanding()+1) / float64(weight)
+}
+
+type roundRobinBalancer struct {
+    next uint64
+}
+
+func (b *roundRobinBalancer)Pick(endpoints []Endpoint) Endpoint {
+    idx := atomic.AddUint64(&b.next, 1) - 1
+    return endpoints[idx%uint64(len(endpoints))]
+}
+
+func (b *roundRobinBalancer)Name() string { return BalancerRoundRobin }
+
+type randomBalancer struct{}
+
+func (b *randomBalancer)Pick(endpoints []Endpoint) Endpoint {
+    return endpoints[rand.Intn(len(endpoints))]
+}
+
+func (b *randomBalancer)Name() string { return BalancerRandom }
+
+// weightedRoundRobinBalancer is the smooth weighted round-robin of nginx, endpoints of a higher
+// weight get more requests without receiving them in bursts.
+type weightedRoundRobinBalancer struct {
+    mu      sync.Mutex
+    current map[string]int
+}
+
+func (b *weightedRoundRobinBalancer)Pick(endpoints []Endpoint) Endpoint {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.current == nil || len(b.current) > len(endpoints) {
+        current := make(map[string]int, len(endpoints))
+        for _, ep := range endpoints {
+            current[ep.Addr()] = b.current[ep.Addr()]
+        }
+        b.current = current
+    }
+
+    var (
+        best  Endpoint
+        total int
+    )
+    for _, ep := range endpoints {
+        weight := ep.Weight()
+        if weight <= 0 {
+            weight = defaultWeight
+        }
+        total += weight
+        b.current[ep.Addr()] += weight
+        if best == nil || b.current[ep.Addr()] > b.current[best.Addr()] {
+            best = ep
+        }
+    }
+    b.current[best.Addr()] -= total
+    return best
+}
+
+func (b *weightedRoundRobinBalancer)Name() string { return BalancerWeightedRoundRobin }
+
+type leastOutstandingBalancer struct{}
+
+// Pick starts at a random endpoint so ties do not all go to the first one.
+func (b *leastOutstandingBalancer)Pick(endpoints []Endpoint) Endpoint {
+    start := rand.Intn(len(endpoints))
+    best := endpoints[start]
+    bestLoad := load(best)
+    for i := 1; i < len(endpoints); i++ {
+        ep := endpoints[(start+i)%len(endpoints)]
+        if l := load(ep); l < bestLoad {
+            best, bestLoad = ep, l
+        }
+    }
+    return best
+}
+
+func (b *leastOutstandingBalancer)Name() string { return BalancerLeastOutstanding }
+
+// p2cBalancer picks the less loaded of two random endpoints, which is close to least-outstanding
+// without sending every new request to the same idle endpoint.
+type p2cBalancer struct{}
+
+func (b *p2cBalancer)Pick(endpoints []Endpoint) Endpoint {
+    if len(endpoints) == 1 {
+        return endpoints[0]
+    }
+    i := rand.Intn(len(endpoints))
+    j := rand.Intn(len(endpoints) - 1)
+    if j >= i {
+        j++
+    }
+    if load(endpoints[j]) < load(endpoints[i]) {
+        return endpoints[j]
+    }
+    return endpoints[i]
+}
+
+func (b *p2cBalancer)Name() string { return BalancerP2C }
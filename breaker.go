@@ -0,0 +1,220 @@
+package wrpc_go
+
+import (
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+)
+
+var ErrCircuitOpen = uerror.NewError(503, "circuit breaker is open")
+
+type BreakerState int32
+
+const (
+    BreakerClosed   BreakerState = 0
+    BreakerOpen     BreakerState = 1
+    BreakerHalfOpen BreakerState = 2
+)
+
+func (s BreakerState)String() string {
+    switch s {
+    case BreakerClosed:
+        return "closed"
+    case BreakerOpen:
+        return "open"
+    case BreakerHalfOpen:
+        return "half-open"
+    }
+    return fmt.Sprintf("BreakerState(%d)", int32(s))
+}
+
+// BreakerConfig configures the circuit breaker of each endpoint. It opens after ConsecutiveFailures
+// failures in a row, or when ErrorRate of at least MinRequests requests failed within Window.
+// Once OpenTimeout has passed HalfOpenRequests probes are let through, it closes if all succeed.
+// The breaker is off unless Enable is set.
+type BreakerConfig struct {
+    Enable              bool          `yaml:"enable"`
+    ConsecutiveFailures int           `yaml:"consecutive-failures"`
+    ErrorRate           float64       `yaml:"error-rate"`
+    MinRequests         int           `yaml:"min-requests"`
+    Window              time.Duration `yaml:"window"`
+    OpenTimeout         time.Duration `yaml:"open-timeout"`
+    HalfOpenRequests    int           `yaml:"half-open-requests"`
+}
+
+func defaultBreakerConfig() *BreakerConfig {
+    return &BreakerConfig{
+        Enable:              false,
+        ConsecutiveFailures: 5,
+        ErrorRate:           0.5,
+        MinRequests:         20,
+        Window:              10000,
+        OpenTimeout:         5000,
+        HalfOpenRequests:    1,
+    }
+}
+
+const (
+    breakerSuccess = 1
+    breakerFailure = 2
+    breakerIgnore  = 3
+)
+
+// breakerResult tells whether err says something about the endpoint: transport errors such as
+// ErrConnClosed, timeouts, a full server and 503/504 are failures. Any other code, the 502 of
+// a handler error included, is the answer of a healthy server, a canceled call is neither.
+func breakerResult(err error) int {
+    if err == nil {
+        return breakerSuccess
+    }
+    e, ok := err.(*uerror.Error)
+    if !ok {
+        return breakerFailure
+    }
+    switch {
+    case e.Code == 499:
+        return breakerIgnore
+    case e.Code == 405 || e.Code == 503 || e.Code == 504:
+        return breakerFailure
+    case *e == *uerror.ErrRequestFull.(*uerror.Error):
+        return breakerFailure
+    }
+    return breakerSuccess
+}
+
+type circuitBreaker struct {
+    conf *BreakerConfig
+
+    mu          sync.Mutex
+    state       BreakerState
+    consecutive int
+    total       int
+    failures    int
+    windowStart time.Time
+    openedAt    time.Time
+    probes      int
+    successes   int
+}
+
+func newCircuitBreaker(conf *BreakerConfig) *circuitBreaker {
+    return &circuitBreaker{
+        conf: conf,
+        windowStart: time.Now(),
+    }
+}
+
+func (b *circuitBreaker)enabled() bool {
+    return b != nil && b.conf != nil && b.conf.Enable
+}
+
+func (b *circuitBreaker)State() BreakerState {
+    if !b.enabled() {
+        return BreakerClosed
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.state
+}
+
+// ready reports whether a request may be sent now, it does not change the state.
+func (b *circuitBreaker)ready() bool {
+    if !b.enabled() {
+        return true
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    switch b.state {
+    case BreakerOpen:
+        return time.Since(b.openedAt) >= b.conf.OpenTimeout
+    case BreakerHalfOpen:
+        return b.probes < b.halfOpenRequests()
+    }
+    return true
+}
+
+// begin is called when a request is sent to the endpoint, an open breaker whose timeout has
+// passed lets it through as a probe.
+func (b *circuitBreaker)begin() {
+    if !b.enabled() {
+        return
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.state == BreakerOpen && time.Since(b.openedAt) >= b.conf.OpenTimeout {
+        b.state = BreakerHalfOpen
+        b.probes = 0
+        b.successes = 0
+    }
+    if b.state == BreakerHalfOpen {
+        b.probes++
+    }
+}
+
+func (b *circuitBreaker)end(result int) {
+    if !b.enabled() {
+        return
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    switch b.state {
+    case BreakerHalfOpen:
+        // a canceled probe frees its slot, otherwise no probe could be sent anymore.
+        if b.probes > 0 {
+            b.probes--
+        }
+        if result == breakerIgnore {
+            return
+        }
+        if result == breakerFailure {
+            b.open()
+            return
+        }
+        b.successes++
+        if b.successes >= b.halfOpenRequests() {
+            b.reset(BreakerClosed)
+        }
+    case BreakerClosed:
+        if result == breakerIgnore {
+            return
+        }
+        if b.conf.Window > 0 && time.Since(b.windowStart) >= b.conf.Window {
+            b.total, b.failures = 0, 0
+            b.windowStart = time.Now()
+        }
+        b.total++
+        if result == breakerSuccess {
+            b.consecutive = 0
+            return
+        }
+        b.failures++
+        b.consecutive++
+        if b.conf.ConsecutiveFailures > 0 && b.consecutive >= b.conf.ConsecutiveFailures {
+            b.open()
+            return
+        }
+        if b.conf.ErrorRate > 0 && b.total >= b.conf.MinRequests && float64(b.failures)/float64(b.total) >= b.conf.ErrorRate {
+            b.open()
+        }
+    }
+}
+
+func (b *circuitBreaker)open() {
+    b.reset(BreakerOpen)
+    b.openedAt = time.Now()
+}
+
+func (b *circuitBreaker)reset(state BreakerState) {
+    b.state = state
+    b.consecutive, b.total, b.failures = 0, 0, 0
+    b.probes, b.successes = 0, 0
+    b.windowStart = time.Now()
+}
+
+func (b *circuitBreaker)halfOpenRequests() int {
+    if b.conf.HalfOpenRequests <= 0 {
+        return 1
+    }
+    return b.conf.HalfOpenRequests
+}
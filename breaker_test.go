@@ -0,0 +1,96 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+)
+
+func TestBreakerRecoversAfterCanceledProbe(t *testing.T) {
+    ts := startServer(t)
+    client := newClient(t, ts,
+        wrpc_go.WithClientOptionBreaker(&wrpc_go.BreakerConfig{
+            Enable: true,
+            ConsecutiveFailures: 1,
+            OpenTimeout: 50 * time.Millisecond,
+            HalfOpenRequests: 1,
+        }),
+        wrpc_go.WithClientOptionRetryPolicy(&wrpc_go.RetryPolicy{MaxAttempts: 1}))
+    state := func() wrpc_go.BreakerState {
+        return client.GetEndpointStatus()[0].Breaker
+    }
+
+    ts.InjectError("Echo", uerror.NewError(503, "down"), 1)
+    if _, err := invoke(client, context.Background(), "Echo", "a"); err == nil {
+        t.Fatal("expected the injected error")
+    }
+    if state() != wrpc_go.BreakerOpen {
+        t.Fatalf("breaker is %v, want open", state())
+    }
+    if _, err := invoke(client, context.Background(), "Echo", "a"); uerror.ParseError(err).Code != 503 {
+        t.Fatalf("open breaker let the call through: %v", err)
+    }
+
+    time.Sleep(60 * time.Millisecond)
+    ctx, cancel := context.WithCancel(context.Background())
+    time.AfterFunc(20 * time.Millisecond, cancel)
+    if _, err := invoke(client, ctx, "Sleep", "500"); uerror.ParseError(err).Code != 499 {
+        t.Fatalf("probe: got %v, want canceled", err)
+    }
+    if state() != wrpc_go.BreakerHalfOpen {
+        t.Fatalf("breaker is %v, want half-open", state())
+    }
+
+    if out, err := invoke(client, context.Background(), "Echo", "b"); err != nil || out != "b" {
+        t.Fatalf("probe after the canceled one: %q %v", out, err)
+    }
+    if state() != wrpc_go.BreakerClosed {
+        t.Fatalf("breaker is %v, want closed", state())
+    }
+}
+
+func TestBreakerOffByDefault(t *testing.T) {
+    ts := startServer(t)
+    client := newClient(t, ts, wrpc_go.WithClientOptionRetryPolicy(&wrpc_go.RetryPolicy{MaxAttempts: 1}))
+    ts.InjectError("Echo", uerror.NewError(503, "down"), 10)
+    for i := 0; i < 10; i++ {
+        invoke(client, context.Background(), "Echo", "a")
+    }
+    if calls := ts.Calls("Echo"); calls != 10 {
+        t.Fatalf("server got %d calls, want 10", calls)
+    }
+}
+
+func TestBreakerIgnoresHandlerErrors(t *testing.T) {
+    ts := startServer(t)
+    client := newClient(t, ts,
+        wrpc_go.WithClientOptionBreaker(&wrpc_go.BreakerConfig{
+            Enable: true,
+            ConsecutiveFailures: 2,
+            OpenTimeout: time.Minute,
+        }),
+        wrpc_go.WithClientOptionRetryPolicy(&wrpc_go.RetryPolicy{MaxAttempts: 1}))
+
+    // a plain error of the handler reaches the client as a 502.
+    ts.InjectError("Echo", errors.New("bad input"), 5)
+    for i := 0; i < 5; i++ {
+        if _, err := invoke(client, context.Background(), "Echo", "a"); uerror.ParseError(err).Code != 502 {
+            t.Fatalf("got %v, want the handler error", err)
+        }
+    }
+    if state := client.GetEndpointStatus()[0].Breaker; state != wrpc_go.BreakerClosed {
+        t.Fatalf("breaker is %v after handler errors, want closed", state)
+    }
+
+    ts.InjectError("Echo", uerror.NewError(503, "down"), 2)
+    for i := 0; i < 2; i++ {
+        invoke(client, context.Background(), "Echo", "a")
+    }
+    if state := client.GetEndpointStatus()[0].Breaker; state != wrpc_go.BreakerOpen {
+        t.Fatalf("breaker is %v after 503s, want open", state)
+    }
+}
@@ -0,0 +1,159 @@
+package wrpc_go
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+)
+
+const defaultBroadcastConcurrency = 32
+
+const (
+    // BroadcastAll waits for every endpoint, each one failing on its own.
+    BroadcastAll = 0
+    // BroadcastFirstN returns as soon as N endpoints succeeded, the other calls are canceled.
+    BroadcastFirstN = 1
+    // BroadcastQuorum returns as soon as a majority of the endpoints succeeded.
+    BroadcastQuorum = 2
+)
+
+type BroadcastOptions struct {
+    mode        int
+    n           int
+    concurrency int
+    timeout     time.Duration
+    meta        map[string]string
+}
+
+type BroadcastOption func(opt *BroadcastOptions)
+
+// WithBroadcastOptionFirstN needs n > 0, Broadcast fails otherwise.
+func WithBroadcastOptionFirstN(n int) BroadcastOption {
+    return func(opt *BroadcastOptions) {
+        opt.mode = BroadcastFirstN
+        opt.n = n
+    }
+}
+
+func WithBroadcastOptionQuorum() BroadcastOption {
+    return func(opt *BroadcastOptions) {
+        opt.mode = BroadcastQuorum
+    }
+}
+
+// WithBroadcastOptionConcurrency limits how many endpoints are called at the same time, 0 means no limit.
+func WithBroadcastOptionConcurrency(concurrency int) BroadcastOption {
+    return func(opt *BroadcastOptions) {
+        opt.concurrency = concurrency
+    }
+}
+
+// WithBroadcastOptionTimeout bounds the call to each endpoint, a slow endpoint does not hold the others.
+func WithBroadcastOptionTimeout(timeout time.Duration) BroadcastOption {
+    return func(opt *BroadcastOptions) {
+        opt.timeout = timeout
+    }
+}
+
+// WithBroadcastOptionMeta sends meta with every call, like the meta of Invoke.
+func WithBroadcastOptionMeta(meta map[string]string) BroadcastOption {
+    return func(opt *BroadcastOptions) {
+        if opt.meta == nil {
+            opt.meta = make(map[string]string)
+        }
+        for k, v := range meta {
+            opt.meta[k] = v
+        }
+    }
+}
+
+type BroadcastResult struct {
+    Addr string
+    Body []byte
+    Err  error
+}
+
+// Broadcast calls method on every endpoint concurrently. Results are in the order of
+// GetAllEndpoints, a call canceled because the broadcast was over fails with ErrRequestCanceled.
+// The error is set when ctx ended first or when the successes the mode needs were not reached.
+func (client *Client)Broadcast(ctx context.Context, encName, method string, in []byte, opts ...BroadcastOption) ([]*BroadcastResult, error) {
+    options := &BroadcastOptions{
+        concurrency: defaultBroadcastConcurrency,
+    }
+    for _, opt := range opts {
+        opt(options)
+    }
+    if options.mode == BroadcastFirstN && options.n <= 0 {
+        return nil, fmt.Errorf("broadcast: first n must be > 0, got %d", options.n)
+    }
+
+    addrs := client.GetAllEndpoints()
+    results := make([]*BroadcastResult, len(addrs))
+    for i, addr := range addrs {
+        results[i] = &BroadcastResult{Addr: addr, Err: uerror.ErrRequestCanceled}
+    }
+    need := len(addrs)
+    switch options.mode {
+    case BroadcastFirstN:
+        need = options.n
+    case BroadcastQuorum:
+        need = len(addrs)/2 + 1
+    }
+
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    type indexedResult struct {
+        idx    int
+        result *BroadcastResult
+    }
+    done := make(chan indexedResult, len(addrs))
+    concurrency := options.concurrency
+    if concurrency <= 0 || concurrency > len(addrs) {
+        concurrency = len(addrs)
+    }
+    sem := make(chan struct{}, concurrency)
+    go func() {
+        for i, addr := range addrs {
+            select {
+            case sem <- struct{}{}:
+            case <- ctx.Done():
+                return
+            }
+            go func(i int, addr string) {
+                defer func() { <- sem }()
+                callCtx := ctx
+                if options.timeout > 0 {
+                    var callCancel context.CancelFunc
+                    callCtx, callCancel = context.WithTimeout(ctx, options.timeout)
+                    defer callCancel()
+                }
+                body, err := client.Invoke(callCtx, encName, addr, method, in, options.meta)
+                done <- indexedResult{idx: i, result: &BroadcastResult{Addr: addr, Body: body, Err: err}}
+            }(i, addr)
+        }
+    }()
+
+    successes, completed := 0, 0
+    for completed < len(addrs) {
+        if options.mode != BroadcastAll && (successes >= need || successes+len(addrs)-completed < need) {
+            break
+        }
+        select {
+        case r := <- done:
+            results[r.idx] = r.result
+            completed++
+            if r.result.Err == nil {
+                successes++
+            }
+        case <- ctx.Done():
+            return results, contextError(ctx)
+        }
+    }
+    if options.mode != BroadcastAll && successes < need {
+        return results, fmt.Errorf("broadcast: %d of %d endpoints succeeded, %d required", successes, len(addrs), need)
+    }
+    return results, nil
+}
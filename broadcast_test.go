@@ -0,0 +1,48 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "encoding/json"
+    "testing"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+)
+
+func TestBroadcastMeta(t *testing.T) {
+    ts1, ts2 := startServer(t), startServer(t)
+    client := newClient(t, ts1,
+        wrpc_go.WithClientOptionAddr(ts1.Addr+";"+ts2.Addr),
+        wrpc_go.WithClientOptionDialer(dialer(ts1, ts2)))
+
+    results, err := client.Broadcast(context.Background(), "json", "Meta", nil,
+        wrpc_go.WithBroadcastOptionMeta(map[string]string{"tenant": "t1"}))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(results) != 2 {
+        t.Fatalf("got %d results, want 2", len(results))
+    }
+    for _, result := range results {
+        meta := map[string]string{}
+        if result.Err != nil {
+            t.Fatalf("%s: %v", result.Addr, result.Err)
+        }
+        if err := json.Unmarshal(result.Body, &meta); err != nil {
+            t.Fatal(err)
+        }
+        if meta["tenant"] != "t1" {
+            t.Fatalf("%s got meta %v", result.Addr, meta)
+        }
+    }
+}
+
+func TestBroadcastFirstNInvalid(t *testing.T) {
+    ts := startServer(t)
+    client := newClient(t, ts)
+    if _, err := client.Broadcast(context.Background(), "json", "Echo", nil, wrpc_go.WithBroadcastOptionFirstN(0)); err == nil {
+        t.Fatal("expected FirstN(0) to fail")
+    }
+    if calls := ts.Calls(""); calls != 0 {
+        t.Fatalf("server got %d calls", calls)
+    }
+}
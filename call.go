@@ -0,0 +1,46 @@
+package wrpc_go
+
+import "context"
+
+// Call is an Invoke running in the background, see Client.Go.
+type Call struct {
+    done chan struct{}
+    body []byte
+    err  error
+}
+
+func newCall() *Call {
+    return &Call{done: make(chan struct{})}
+}
+
+// NewErrorCall returns a Call that is already done with err, e.g. when the request could not be encoded.
+func NewErrorCall(err error) *Call {
+    call := newCall()
+    call.finish(nil, err)
+    return call
+}
+
+func (call *Call)finish(body []byte, err error) {
+    call.body, call.err = body, err
+    close(call.done)
+}
+
+// Done is closed once the result is available.
+func (call *Call)Done() <-chan struct{} {
+    return call.done
+}
+
+// Result waits for the call to complete and returns what Invoke returned.
+func (call *Call)Result() ([]byte, error) {
+    <- call.done
+    return call.body, call.err
+}
+
+// Go is the asynchronous Invoke, it returns at once and the result is read from the Call.
+func (client *Client)Go(ctx context.Context, encName, addr, method string, in []byte, opt ...map[string]string) *Call {
+    call := newCall()
+    go func() {
+        call.finish(client.Invoke(ctx, encName, addr, method, in, opt...))
+    }()
+    return call
+}
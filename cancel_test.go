@@ -0,0 +1,41 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+)
+
+// handlerContext reports the context of every call of method once its handler returned.
+func handlerContext(method string, ctxs chan<- context.Context) wrpc_go.ServerOption {
+    return wrpc_go.WithServerOptionInterceptors(func(ctx context.Context, req *wrpc_go.Request, info *wrpc_go.ServerInfo, handler wrpc_go.ServerHandler) ([]byte, error) {
+        out, err := handler(ctx, req)
+        if req.Method == method {
+            ctxs <- ctx
+        }
+        return out, err
+    })
+}
+
+func TestCancelReachesHandler(t *testing.T) {
+    ctxs := make(chan context.Context, 1)
+    ts := startServer(t, handlerContext("Sleep", ctxs))
+    client := newClient(t, ts)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    time.AfterFunc(50 * time.Millisecond, cancel)
+    if _, err := invoke(client, ctx, "Sleep", "5000"); uerror.ParseError(err).Code != 499 {
+        t.Fatalf("got %v, want canceled", err)
+    }
+    select {
+    case hctx := <- ctxs:
+        if hctx.Err() != context.Canceled {
+            t.Fatalf("handler context ended with %v, want canceled", hctx.Err())
+        }
+    case <- time.After(time.Second):
+        t.Fatal("the handler kept running after the client canceled")
+    }
+}
@@ -2,6 +2,7 @@ package wrpc_go
 
 import (
     "context"
+    "crypto/tls"
     "fmt"
     "github.com/serialx/hashring"
     "github.com/wukong-cloud/wrpc-go/internal/discovery"
@@ -14,7 +15,10 @@ import (
     "time"
 )
 
-var ErrConnectNotFound = fmt.Errorf("connect not found")
+var (
+    ErrConnectNotFound = fmt.Errorf("connect not found")
+    ErrClientClosed    = uerror.NewError(410, "client closed")
+)
 
 var requestId int64
 
@@ -29,16 +33,37 @@ func nextRequestId() int64 {
 type ClientOptions struct {
     addr           string
     maxConn        int
+    minConn        int
     requestTimeout time.Duration
     maxIdleTime    time.Duration
+    connectTimeout time.Duration
     readSize       int32
     encodeType     string
     reTry          int
     discover       discovery.Discover
+    interceptors   []ClientInterceptor
+    streamInterceptors []ClientStreamInterceptor
+    protocolVersion uint8
+    checksum       bool
+    balancer       Balancer
+    breaker        *BreakerConfig
+    outlier        *OutlierConfig
+    retry          *RetryPolicy
+    hedge          map[string]*HedgePolicy
+    compress       string
+    compressThreshold int
+    maxMessageSize int
+    heartbeatInterval time.Duration
+    heartbeatTimeout  time.Duration
+    tls            *TLSConfig
+    tlsConfig      *tls.Config
+    dialer         Dialer
 }
 
 type ClientOption func(opt *ClientOptions)
 
+// WithClientOptionAddr sets the endpoints, separated by ";", a unix domain socket is given as
+// "unix:///path/to/socket".
 func WithClientOptionAddr(addr string) ClientOption {
     return func(opt *ClientOptions) {
         opt.addr = addr
@@ -51,6 +76,69 @@ func WithClientOptionMaxConn(max int) ClientOption {
     }
 }
 
+// WithClientOptionMinConn keeps n connections open to every endpoint, they are dialed as soon
+// as the endpoint is discovered and are not reaped when idle.
+func WithClientOptionMinConn(n int) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.minConn = n
+    }
+}
+
+// WithClientOptionMaxIdleTime closes connections that started no call for d, 0 keeps them.
+func WithClientOptionMaxIdleTime(d time.Duration) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.maxIdleTime = d
+    }
+}
+
+// WithClientOptionMaxMessageSize bounds the size a compressed response body decompresses to, the
+// call fails with uerror.ErrMessageTooLarge beyond it. size <= 0 removes the bound.
+func WithClientOptionMaxMessageSize(size int) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.maxMessageSize = size
+    }
+}
+
+// WithClientOptionConnectTimeout bounds the dial of a connection, TLS handshake excluded,
+// 0 waits as long as the dialer does.
+func WithClientOptionConnectTimeout(d time.Duration) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.connectTimeout = d
+    }
+}
+
+// WithClientOptionCompressor compresses request bodies of at least threshold bytes with the
+// compressor registered as name, and asks the server to compress the responses the same way.
+func WithClientOptionCompressor(name string, threshold int) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.compress = name
+        opt.compressThreshold = threshold
+    }
+}
+
+// WithClientOptionTLS dials the endpoints with TLS, the certificates of conf are reloaded
+// when they change.
+func WithClientOptionTLS(conf *TLSConfig) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.tls = conf
+    }
+}
+
+// WithClientOptionTLSConfig dials the endpoints with TLS using config, it takes precedence over
+// WithClientOptionTLS.
+func WithClientOptionTLSConfig(config *tls.Config) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.tlsConfig = config
+    }
+}
+
+// WithClientOptionDialer opens the connections with dialer instead of net.Dial, TLS still runs on top.
+func WithClientOptionDialer(dialer Dialer) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.dialer = dialer
+    }
+}
+
 func WithClientOptionEncodeType(encodeType string) ClientOption {
     return func(opt *ClientOptions) {
         opt.encodeType = encodeType
@@ -63,19 +151,137 @@ func WithClientOptionsDiscover(discover discovery.Discover) ClientOption {
     }
 }
 
+func WithClientOptionInterceptors(interceptors ...ClientInterceptor) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.interceptors = append(opt.interceptors, interceptors...)
+    }
+}
+
+// WithClientOptionStreamInterceptors wraps NewStream, they run in the given order.
+func WithClientOptionStreamInterceptors(interceptors ...ClientStreamInterceptor) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.streamInterceptors = append(opt.streamInterceptors, interceptors...)
+    }
+}
+
+// WithClientOptionProtocolVersion sets the frame version sent to servers. It is ProtocolV1 by
+// default, ProtocolV2 must only be used once every server understands the v2 header, it brings
+// heartbeats, cancel frames and checksums.
+func WithClientOptionProtocolVersion(version uint8) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.protocolVersion = version
+    }
+}
+
+func WithClientOptionChecksum(checksum bool) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.checksum = checksum
+    }
+}
+
+// WithClientOptionBalancer sets how requests without an address or a consistent hash key
+// are spread over the endpoints, round-robin by default.
+func WithClientOptionBalancer(balancer Balancer) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.balancer = balancer
+    }
+}
+
+func WithClientOptionBreaker(conf *BreakerConfig) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.breaker = conf
+    }
+}
+
+// WithClientOptionRetryPolicy replaces the retry policy, which is used as it is but for
+// MaxAttempts that defaults to the retry of client-config.
+func WithClientOptionRetryPolicy(policy *RetryPolicy) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.retry = policy
+    }
+}
+
+// WithClientOptionHedgePolicy hedges the calls of method, see HedgePolicy.
+func WithClientOptionHedgePolicy(method string, policy *HedgePolicy) ClientOption {
+    return func(opt *ClientOptions) {
+        if opt.hedge == nil {
+            opt.hedge = make(map[string]*HedgePolicy)
+        }
+        opt.hedge[method] = policy
+    }
+}
+
+func WithClientOptionOutlierDetection(conf *OutlierConfig) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.outlier = conf
+    }
+}
+
+// WithClientOptionHeartbeat pings a connection that received nothing for interval and closes it
+// when the pong does not arrive within timeout, its pending calls fail at once. An interval <= 0
+// turns heartbeats off, they need ProtocolV2.
+func WithClientOptionHeartbeat(interval, timeout time.Duration) ClientOption {
+    return func(opt *ClientOptions) {
+        opt.heartbeatInterval = interval
+        opt.heartbeatTimeout = timeout
+    }
+}
+
 func loadClientOptions(opts ...ClientOption) *ClientOptions {
     cfg := GetClientConfig()
     options := &ClientOptions{
         requestTimeout: cfg.RequestTimeout,
         readSize: cfg.ReadBufferSize,
         maxConn: cfg.Thread,
+        minConn: cfg.MinConn,
         maxIdleTime: cfg.MaxIdleTime,
+        connectTimeout: cfg.ConnectTimeout,
         encodeType: cfg.EncodeType,
         reTry: cfg.ReTry,
+        protocolVersion: cfg.ProtocolVersion,
+        checksum: cfg.Checksum,
+        balancer: NewBalancer(cfg.Balancer),
+        breaker: cfg.Breaker,
+        outlier: cfg.OutlierDetection,
+        retry: cfg.RetryPolicy,
+        hedge: make(map[string]*HedgePolicy),
+        compress: cfg.Compress,
+        compressThreshold: cfg.CompressThreshold,
+        maxMessageSize: cfg.MaxMessageSize,
+        tls: cfg.TLS,
+        heartbeatInterval: cfg.HeartbeatInterval,
+        heartbeatTimeout: cfg.HeartbeatTimeout,
+    }
+    for method, policy := range cfg.HedgePolicy {
+        options.hedge[method] = policy
     }
     for _, opt := range opts {
         opt(options)
     }
+    if options.protocolVersion != ProtocolV2 {
+        options.protocolVersion = ProtocolV1
+    }
+    if options.maxConn <= 0 {
+        options.maxConn = 1
+    }
+    if options.minConn > options.maxConn {
+        options.minConn = options.maxConn
+    }
+    if options.balancer == nil {
+        options.balancer = NewBalancer(BalancerRoundRobin)
+    }
+    // a copy, the policy may be shared with other clients.
+    retry := &RetryPolicy{}
+    if options.retry != nil {
+        *retry = *options.retry
+    }
+    if retry.MaxAttempts <= 0 {
+        retry.MaxAttempts = options.reTry
+    }
+    if retry.MaxAttempts <= 0 {
+        retry.MaxAttempts = 1
+    }
+    options.retry = retry
     return options
 }
 
@@ -84,12 +290,19 @@ type Client struct {
     opts *ClientOptions
     mu   sync.Mutex
     protocol Protocol
-    idx int
     connectors []*connector
     reqMap map[int64]chan *Response
     rwLock sync.Mutex
     discover discovery.Discover
     hasher *hashring.HashRing
+    interceptor ClientInterceptor
+    streamInterceptor ClientStreamInterceptor
+    retryBudget *retryBudget
+    errorRate   errorRate
+    closed      chan struct{}
+    closeOnce   sync.Once
+    tlsConfig   func() *tls.Config
+    tlsErr      error
 }
 
 func NewClient(name string, opts ...ClientOption) *Client {
@@ -101,12 +314,24 @@ func NewClient(name string, opts ...ClientOption) *Client {
         reqMap: make(map[int64]chan *Response),
         discover: discovery.NewDiscover(conf.DiscoverConfig),
         hasher: hashring.New([]string{}),
+        closed: make(chan struct{}),
     }
     client.opts = loadClientOptions(opts...)
     if client.opts.discover != nil {
         client.discover = client.opts.discover
     }
+    client.interceptor = chainClientInterceptors(client.opts.interceptors)
+    client.streamInterceptor = chainClientStreamInterceptors(client.opts.streamInterceptors)
+    client.retryBudget = newRetryBudget(client.opts.retry)
+    if config := client.opts.tlsConfig; config != nil {
+        client.tlsConfig = func() *tls.Config { return config }
+    } else if client.opts.tls != nil {
+        // a bad certificate fails the dials, so the calls report it.
+        client.tlsConfig, client.tlsErr = newClientTLSConfig(client.opts.tls)
+    }
     client.initConnect()
+    go client.detectOutliers()
+    go client.maintainPool()
     return client
 }
 
@@ -120,13 +345,17 @@ func (client *Client)initConnect() {
     }
     go func() {
         timer := time.NewTicker(time.Second*10)
+        defer timer.Stop()
+        watch := client.discover.Watch(client.name)
         for {
             select {
             case <- timer.C:
                 endpoints := client.discover.Find(client.name)
                 client.updateConnector(strings.Join(endpoints, ";"), false)
-            case endpoints := <- client.discover.Watch(client.name):
+            case endpoints := <- watch:
                 client.updateConnector(strings.Join(endpoints, ";"), false)
+            case <- client.closed:
+                return
             }
         }
     }()
@@ -134,30 +363,46 @@ func (client *Client)initConnect() {
 
 func (client *Client)updateConnector(addr string, isFixed bool) {
     client.mu.Lock()
+    if client.isClosed() {
+        client.mu.Unlock()
+        return
+    }
 
     addrs := strings.Split(addr, ";")
     oldConnectors := client.connectors
     newConnectors := make([]*connector, 0)
+    existing := make(map[string]*connector, len(oldConnectors))
+    for _, oldConn := range oldConnectors {
+        existing[oldConn.addr] = oldConn
+    }
 
+    // endpoints already known keep their connector, so their connections, breaker and
+    // outlier state survive a refresh of the discovery.
+    found := make(map[string]bool)
+    added := make([]*connector, 0)
     for _, addr := range addrs {
         addr := strings.TrimSpace(addr)
         if addr == "" {
             continue
         }
+        host, weight := parseEndpoint(addr)
+        if found[host] {
+            continue
+        }
+        found[host] = true
+        if connect, ok := existing[host]; ok {
+            atomic.StoreInt32(&connect.weight, int32(weight))
+            newConnectors = append(newConnectors, connect)
+            continue
+        }
         connect := newConnector(client, addr, isFixed)
         newConnectors = append(newConnectors, connect)
+        added = append(added, connect)
     }
 
     delConnectoers := make([]*connector, 0)
     for _, oldConn := range oldConnectors {
-        isFound := false
-        for _, newConn := range newConnectors {
-            if oldConn.addr == newConn.addr {
-                isFound = true
-                break
-            }
-        }
-        if isFound {
+        if found[oldConn.addr] {
             continue
         }
         if oldConn.isFixed {
@@ -168,12 +413,16 @@ func (client *Client)updateConnector(addr string, isFixed bool) {
     }
     newNodes := map[string]int{}
     for _, node := range newConnectors {
-        newNodes[node.addr] = 10
+        newNodes[node.addr] = 10 * node.Weight()
     }
     client.hasher = hashring.NewWithWeights(newNodes)
     client.connectors = newConnectors
     client.mu.Unlock()
 
+    for _, connect := range added {
+        go connect.warm()
+    }
+
     if len(delConnectoers) > 0 {
         go func() {
             for _, delConn := range delConnectoers {
@@ -183,14 +432,26 @@ func (client *Client)updateConnector(addr string, isFixed bool) {
     }
 }
 
-func (client *Client)connector(key string, findType int) *connector {
+// connector finds the endpoint of a request, endpoints in exclude are only chosen when
+// there is no other one.
+func (client *Client)connector(key string, findType int, exclude map[*connector]bool) (*connector, error) {
     switch findType {
     case findType_addr:
-        return client.findConnector(key)
+        connect := client.findConnector(key)
+        if connect == nil {
+            return nil, ErrConnectNotFound
+        }
+        if !connect.breaker.ready() {
+            return nil, ErrCircuitOpen
+        }
+        return connect, nil
     case findType_consistentHash:
-        return client.consistentHashConnector(key)
+        if connect := client.consistentHashConnector(key); connect != nil && !exclude[connect] && connect.available() && !connect.isDraining() {
+            return connect, nil
+        }
+        return client.nextConnector(exclude)
     default:
-        return client.nextConnector()
+        return client.nextConnector(exclude)
     }
 }
 
@@ -219,23 +480,57 @@ func (client *Client)consistentHashConnector(key string) *connector {
     return client.findConnector(node)
 }
 
-func (client *Client)nextConnector() *connector {
-    var connect *connector
+// hasUntried tells whether nextConnector can pick an endpoint that is not in tried.
+func (client *Client)hasUntried(tried map[*connector]bool) bool {
+    client.mu.Lock()
+    defer client.mu.Unlock()
+    for _, connect := range client.connectors {
+        if !tried[connect] && connect.available() && !connect.isDraining() {
+            return true
+        }
+    }
+    return false
+}
+
+// nextConnector lets the balancer pick among the endpoints whose breaker is not open and
+// that are not ejected. Excluded, draining and ejected endpoints, in this order, are only
+// used when there is nothing else.
+func (client *Client)nextConnector(exclude map[*connector]bool) (*connector, error) {
+    filters := []func(c *connector) bool{
+        func(c *connector) bool { return !exclude[c] && c.available() && !c.isDraining() },
+        func(c *connector) bool { return c.available() && !c.isDraining() },
+        func(c *connector) bool { return c.available() },
+        func(c *connector) bool { return c.breaker.ready() },
+    }
     client.mu.Lock()
     connectNum := len(client.connectors)
+    endpoints := make([]Endpoint, 0, connectNum)
+    for _, filter := range filters {
+        for _, connect := range client.connectors {
+            if filter(connect) {
+                endpoints = append(endpoints, connect)
+            }
+        }
+        if len(endpoints) > 0 {
+            break
+        }
+    }
+    client.mu.Unlock()
     if connectNum == 0 {
-        client.mu.Unlock()
-        return nil
+        return nil, ErrConnectNotFound
     }
-    if client.idx >= connectNum {
-        client.idx = 0
+    if len(endpoints) == 0 {
+        return nil, ErrCircuitOpen
     }
-    connect = client.connectors[client.idx]
-    client.idx++
-    client.mu.Unlock()
-    return connect
+    connect, _ := client.opts.balancer.Pick(endpoints).(*connector)
+    if connect == nil {
+        return nil, ErrConnectNotFound
+    }
+    return connect, nil
 }
 
+// GetAllEndpoints returns the address of every endpoint, ejected or draining ones included.
+// Their state is returned by GetEndpointStatus, as changing the result here would break the callers.
 func (client *Client)GetAllEndpoints() []string {
     addrs := make([]string, 0)
     client.mu.Lock()
@@ -246,25 +541,142 @@ func (client *Client)GetAllEndpoints() []string {
     return addrs
 }
 
+// GetEndpointStatus returns the same endpoints as GetAllEndpoints with their state.
+func (client *Client)GetEndpointStatus() []EndpointStatus {
+    client.mu.Lock()
+    connectors := append([]*connector(nil), client.connectors...)
+    client.mu.Unlock()
+    status := make([]EndpointStatus, 0, len(connectors))
+    for _, connect := range connectors {
+        status = append(status, connect.status())
+    }
+    return status
+}
+
 func (client *Client)Invoke(ctx context.Context, encName, addr, method string, in []byte, opt ...map[string]string) ([]byte, error) {
+    if client.isClosed() {
+        return nil, ErrClientClosed
+    }
     var cancel context.CancelFunc
     if client.opts.requestTimeout > 0 {
         ctx, cancel = context.WithTimeout(ctx, client.opts.requestTimeout)
         defer cancel()
     }
-    metadata, ok := FromOutgoingContext(ctx)
-    if !ok {
-        metadata = make(map[string]string)
+    metadata := make(Meta)
+    if md, ok := FromOutgoingContext(ctx); ok {
+        for k, v := range md {
+            metadata[k] = v
+        }
+    }
+    for _, md := range opt {
+        for k, v := range md {
+            metadata[k] = v
+        }
     }
     if encName == "" {
         encName = client.opts.encodeType
     }
-    metadata.Set(EncodeType, encName)
-    req := &Request{
-        RequestId: nextRequestId(),
+    call := &ClientCall{
         Method: method,
-        Body: in,
+        Addr: addr,
+        EncodeType: encName,
         Meta: metadata,
+        Body: in,
+    }
+    if client.interceptor == nil {
+        return client.invoke(ctx, call)
+    }
+    return client.interceptor(ctx, call, client.invoke)
+}
+
+// invoke sends the call and retries or hedges it as the policies of the method allow.
+func (client *Client)invoke(ctx context.Context, call *ClientCall) ([]byte, error) {
+    if call.Meta == nil {
+        call.Meta = make(Meta)
+    }
+    call.Meta.Set(EncodeType, call.EncodeType)
+    wire, err := client.compressCall(call)
+    if err != nil {
+        return nil, err
+    }
+    defer func() {
+        call.Addr = wire.Addr
+    }()
+    return client.send(ctx, wire)
+}
+
+// send runs the retry or hedge policy of the method.
+func (client *Client)send(ctx context.Context, call *ClientCall) ([]byte, error) {
+    if hedge, ok := client.opts.hedge[call.Method]; ok && hedge != nil && hedge.MaxHedges > 0 {
+        return client.hedge(ctx, call, hedge)
+    }
+
+    policy := client.opts.retry
+    client.retryBudget.deposit()
+    addr := call.Addr
+    tried := make(map[*connector]bool)
+    for attempt := 1; ; attempt++ {
+        body, state, err := client.attemptTimeout(ctx, call, addr, tried, policy.PerAttemptTimeout)
+        if err == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil || client.isClosed() {
+            return body, err
+        }
+        switch state {
+        case attemptSent:
+            if !policy.retryable(call.Method, err) {
+                return nil, err
+            }
+        case attemptNotSent:
+            return nil, err
+        }
+        if !client.retryBudget.withdraw() || !sleepContext(ctx, policy.backoff(attempt)) {
+            return nil, err
+        }
+    }
+}
+
+const (
+    // attemptNotSent means no endpoint could take the request, trying again would not help.
+    attemptNotSent     = 1
+    attemptWriteFailed = 2
+    attemptSent        = 3
+)
+
+// attemptTimeout is attempt bounded by timeout when it is > 0.
+func (client *Client)attemptTimeout(ctx context.Context, call *ClientCall, addr string, tried map[*connector]bool, timeout time.Duration) ([]byte, int, error) {
+    if timeout <= 0 {
+        return client.attempt(ctx, call, addr, tried)
+    }
+    ctx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+    return client.attempt(ctx, call, addr, tried)
+}
+
+func (client *Client)attempt(ctx context.Context, call *ClientCall, addr string, tried map[*connector]bool) ([]byte, int, error) {
+    pc, state, err := client.start(ctx, call, addr, tried)
+    if err != nil {
+        return nil, state, err
+    }
+    call.Addr = pc.conn.connect.addr
+    body, err := pc.wait(ctx)
+    return body, state, err
+}
+
+// pendingCall is a request written to an endpoint whose response is not read yet.
+type pendingCall struct {
+    client   *Client
+    req      *Request
+    conn     *clientConn
+    respChan chan *Response
+    start    time.Time
+}
+
+func (client *Client)start(ctx context.Context, call *ClientCall, addr string, tried map[*connector]bool) (*pendingCall, int, error) {
+    req := &Request{
+        RequestId: nextRequestId(),
+        Method: call.Method,
+        Body: call.Body,
+        Meta: call.Meta,
+        Timeout: remainingTimeout(ctx),
     }
 
     respChan := make(chan *Response, 1)
@@ -272,32 +684,157 @@ func (client *Client)Invoke(ctx context.Context, encName, addr, method string, i
     client.reqMap[req.RequestId] = respChan
     client.rwLock.Unlock()
 
-    err := client.sendRequest(addr, req)
+    conn, state, err := client.sendRequest(addr, req, tried)
     if err != nil {
         client.rwLock.Lock()
         delete(client.reqMap, req.RequestId)
         close(respChan)
         client.rwLock.Unlock()
-        return nil, err
+        return nil, state, err
     }
+    atomic.AddInt64(&conn.connect.outstanding, 1)
+    pc := &pendingCall{
+        client: client,
+        req: req,
+        conn: conn,
+        respChan: respChan,
+        start: time.Now(),
+    }
+    return pc, state, nil
+}
+
+func (pc *pendingCall)wait(ctx context.Context) (body []byte, err error) {
+    client, connect := pc.client, pc.conn.connect
+    defer func() {
+        atomic.AddInt64(&connect.outstanding, -1)
+        result := breakerResult(err)
+        connect.breaker.end(result)
+        connect.outlier.record(result, time.Since(pc.start))
+        client.errorRate.record(result)
+    }()
 
     select {
     case <- ctx.Done():
         client.rwLock.Lock()
-        delete(client.reqMap, req.RequestId)
-        close(respChan)
+        delete(client.reqMap, pc.req.RequestId)
+        close(pc.respChan)
         client.rwLock.Unlock()
-        return nil, uerror.ErrRequestTimeout
-    case resp, ok := <- respChan:
+        pc.conn.removePending(pc.req.RequestId)
+        pc.conn.cancel(pc.req.RequestId)
+        return nil, contextError(ctx)
+    case resp, ok := <- pc.respChan:
         if !ok {
             return nil, uerror.NewError(502, "chan is closed")
         }
-        close(respChan)
+        close(pc.respChan)
         if resp.Code > 0 && resp.Code != 200 {
             return nil, uerror.NewError(resp.Code, resp.CodeStatus)
         }
-        return resp.Body, nil
+        return decompress(resp.Meta[respCompressKey], client.opts.maxMessageSize, resp.Body)
+    }
+}
+
+// compressCall returns the call as it is sent: the body compressed with the compressor of the
+// call or else the client's, which is advertised for the response. call is left as it is, an
+// interceptor may invoke it again.
+func (client *Client)compressCall(call *ClientCall) (*ClientCall, error) {
+    name := call.Meta.Get(CompressType)
+    if name == "" {
+        name = client.opts.compress
+    }
+    wire := *call
+    wire.Meta = make(Meta, len(call.Meta)+2)
+    for k, v := range call.Meta {
+        if k != CompressType && k != reqCompressKey && k != acceptCompressKey {
+            wire.Meta[k] = v
+        }
+    }
+    if name == "" || name == CompressNone {
+        return &wire, nil
+    }
+    body, used, err := compress(name, client.opts.compressThreshold, call.Body)
+    if err != nil {
+        return nil, err
+    }
+    wire.Body = body
+    if used != "" {
+        wire.Meta.Set(reqCompressKey, used)
+    }
+    wire.Meta.Set(acceptCompressKey, name)
+    return &wire, nil
+}
+
+func contextError(ctx context.Context) error {
+    if ctx.Err() == context.Canceled {
+        return uerror.ErrRequestCanceled
+    }
+    return uerror.ErrRequestTimeout
+}
+
+// Close stops the discovery refresh, fails the pending calls with ErrClientClosed and closes
+// every connection. Calls made after Close return ErrClientClosed.
+func (client *Client)Close() error {
+    client.closeOnce.Do(func() {
+        close(client.closed)
+        client.mu.Lock()
+        connectors := client.connectors
+        client.connectors = make([]*connector, 0)
+        client.hasher = hashring.New([]string{})
+        client.mu.Unlock()
+
+        client.failPending(ErrClientClosed, nil)
+        for _, connect := range connectors {
+            connect.close()
+        }
+    })
+    return nil
+}
+
+func (client *Client)isClosed() bool {
+    select {
+    case <- client.closed:
+        return true
+    default:
+        return false
+    }
+}
+
+// failPending answers the calls of ids waiting in reqMap with err, all of them when ids is nil.
+func (client *Client)failPending(err error, ids []int64) {
+    werr := uerror.ParseError(err)
+    client.rwLock.Lock()
+    if ids == nil {
+        for id := range client.reqMap {
+            ids = append(ids, id)
+        }
+    }
+    for _, id := range ids {
+        respChan, ok := client.reqMap[id]
+        if !ok {
+            continue
+        }
+        delete(client.reqMap, id)
+        respChan <- &Response{
+            RequestId: id,
+            Code: werr.Code,
+            CodeStatus: werr.ErrMsg,
+        }
     }
+    client.rwLock.Unlock()
+}
+
+// remainingTimeout is the budget left in ctx in milliseconds, it is sent with the request
+// so the server does not work longer than the caller waits.
+func remainingTimeout(ctx context.Context) int64 {
+    deadline, ok := ctx.Deadline()
+    if !ok {
+        return 0
+    }
+    timeout := int64(time.Until(deadline) / time.Millisecond)
+    if timeout <= 0 {
+        timeout = 1
+    }
+    return timeout
 }
 
 const (
@@ -306,97 +843,143 @@ const (
     findType_consistentHash = 3
 )
 
-func (client *Client)sendRequest(addr string, req *Request) error {
+func findKey(addr string, meta Meta) (string, int) {
+    if addr != "" {
+        return addr, findType_addr
+    }
+    if hash, ok := meta[ConsistentHashKey]; ok {
+        return hash, findType_consistentHash
+    }
+    return "", findType_next
+}
+
+func (client *Client)packRequest(req *Request) ([]byte, error) {
     bs, err := client.protocol.PacketRequest(req)
     if err != nil {
-        return  err
+        return nil, err
     }
+    return client.packFrame(msgTypeRequest, bs), nil
+}
 
-    tryTime := client.opts.reTry
-    if tryTime <= 0 {
-        tryTime = 1
+func (client *Client)packFrame(msgType uint8, payload []byte) []byte {
+    var flags uint8
+    if client.opts.checksum {
+        flags |= flagChecksum
     }
+    return encodeFrame(client.opts.protocolVersion, msgType, flags, payload)
+}
 
-    findType := findType_next
-    key := ""
-    if addr != "" {
-        key = addr
-        findType = findType_addr
-    } else if hash, ok := req.Meta[ConsistentHashKey]; ok {
-        key = hash
-        findType = findType_consistentHash
+// sendRequest writes req to an endpoint not in tried, which it is added to.
+func (client *Client)sendRequest(addr string, req *Request, tried map[*connector]bool) (*clientConn, int, error) {
+    bs, err := client.packRequest(req)
+    if err != nil {
+        return nil, attemptNotSent, err
     }
 
-    for i := 0; i < tryTime; i++ {
-        if i > 0 && findType != findType_addr {
-            findType = findType_next
-        }
-        connect := client.connector(key, findType)
-        if connect == nil {
-            return ErrConnectNotFound
-        }
-        conn, cerr := connect.getConn()
-        if cerr != nil {
-            time.Sleep(time.Millisecond*10)
-            err = cerr
-            continue
-        }
-        if serr := conn.send(bs); serr != nil {
-            time.Sleep(time.Millisecond*10)
-            err = cerr
-            continue
+    key, findType := findKey(addr, req.Meta)
+    connect, err := client.connector(key, findType, tried)
+    if err != nil {
+        return nil, attemptNotSent, err
+    }
+    if tried != nil {
+        tried[connect] = true
+    }
+    connect.breaker.begin()
+    conn, err := connect.getConn()
+    if err == nil {
+        err = conn.addPending(req.RequestId)
+    }
+    if err == nil {
+        if err = conn.writeWait(bs); err != nil {
+            conn.removePending(req.RequestId)
         }
-        err = nil
-        break
     }
-    return err
+    if err != nil {
+        connect.breaker.end(breakerFailure)
+        return nil, attemptWriteFailed, err
+    }
+    return conn, attemptSent, nil
 }
 
 type connector struct {
     addr    string
+    weight  int32
+    outstanding int64
     client  *Client
     nextId  int32
     idx     int
     conns   []*clientConn
+    // dialing is closed once the dial of getConn in flight is over, nil when there is none.
+    dialing chan struct{}
     mu      sync.Mutex
     callNum int
     isFixed bool
+    // draining counts the connections that got a goAway and are still open.
+    draining int32
+    closed  bool
+    breaker *circuitBreaker
+    outlier *outlierStats
 }
 
 func newConnector(client *Client, addr string, isFixed bool) *connector {
+    addr, weight := parseEndpoint(addr)
     c := &connector{
         client: client,
         addr: addr,
+        weight: int32(weight),
+        breaker: newCircuitBreaker(client.opts.breaker),
+        outlier: newOutlierStats(),
         isFixed: isFixed,
         conns: make([]*clientConn, 0, client.opts.maxConn),
     }
     return c
 }
 
+// getConn returns the next connection, dialing one while there are less than maxConn. The dial
+// runs without the lock so the calls on the other connections do not wait for it, and only one
+// runs at a time: without connection the calls wait for it, otherwise they use the others.
 func (c *connector)getConn() (*clientConn, error) {
     c.mu.Lock()
-    connNum := len(c.conns)
-    if connNum == 0 {
-        conn, err := net.Dial("tcp", c.addr)
-        if err != nil {
+    for {
+        if c.closed {
             c.mu.Unlock()
-            return nil, err
+            return nil, ErrConnClosed
         }
-        clientConn := newClientConn(c, conn)
-        c.conns = append(c.conns, clientConn)
-        connNum++
-    }
-    if c.idx >= connNum {
-        if connNum < c.client.opts.maxConn && c.idx < c.client.opts.maxConn {
-            conn, err := net.Dial("tcp", c.addr)
-            if err == nil {
-                clientConn := newClientConn(c, conn)
-                c.conns = append(c.conns, clientConn)
-                connNum++
+        connNum := len(c.conns)
+        grow := connNum < c.client.opts.maxConn && c.idx >= connNum && c.idx < c.client.opts.maxConn
+        if connNum > 0 && (!grow || c.dialing != nil) {
+            break
+        }
+        if c.dialing != nil {
+            dialing := c.dialing
+            c.mu.Unlock()
+            <- dialing
+            c.mu.Lock()
+            continue
+        }
+        dialing := make(chan struct{})
+        c.dialing = dialing
+        c.mu.Unlock()
+        rw, err := c.client.dial(c.addr)
+        c.mu.Lock()
+        c.dialing = nil
+        close(dialing)
+        if err != nil {
+            if len(c.conns) == 0 {
+                c.mu.Unlock()
+                return nil, err
             }
+            break
+        }
+        if c.closed {
+            c.mu.Unlock()
+            rw.Close()
+            return nil, ErrConnClosed
         }
+        c.conns = append(c.conns, newClientConn(c, rw))
+        break
     }
-    if c.idx >= connNum {
+    if c.idx >= len(c.conns) {
         c.idx = 0
     }
     conn := c.conns[c.idx]
@@ -405,6 +988,18 @@ func (c *connector)getConn() (*clientConn, error) {
     return conn, nil
 }
 
+func (c *connector)Addr() string {
+    return c.addr
+}
+
+func (c *connector)Weight() int {
+    return int(atomic.LoadInt32(&c.weight))
+}
+
+func (c *connector)Outstanding() int64 {
+    return atomic.LoadInt64(&c.outstanding)
+}
+
 func (c *connector)nextConnId() int32 {
     id := atomic.AddInt32(&c.nextId, 1)
     return id
@@ -423,8 +1018,23 @@ func (c *connector)removeConn(connId int32) {
     c.mu.Unlock()
 }
 
+// isDraining reports whether a connection of the endpoint got a goAway and is still open,
+// new requests prefer the other endpoints meanwhile.
+func (c *connector)isDraining() bool {
+    return atomic.LoadInt32(&c.draining) > 0
+}
+
+func (c *connector)available() bool {
+    return c.breaker.ready() && !c.outlier.ejected()
+}
+
 func (c *connector)close() {
-    for _, conn := range c.conns {
+    c.mu.Lock()
+    c.closed = true
+    conns := c.conns
+    c.conns = make([]*clientConn, 0)
+    c.mu.Unlock()
+    for _, conn := range conns {
         conn.close()
     }
 }
@@ -433,50 +1043,89 @@ type clientConn struct {
     connId   int32
     connect  *connector
     rw       net.Conn
+    writer   *connWriter
     running  bool
     callNum  int32
     createAt time.Time
     useAt    time.Time
     mu       sync.Mutex
+    streams  map[int64]*clientStream
+    draining bool
+    pending  map[int64]struct{}
+    heartbeat *heartbeat
+    done     chan struct{}
 }
 
 func newClientConn(c *connector, rw net.Conn) *clientConn {
+    opts := c.client.opts
+    now := time.Now()
     conn := &clientConn{
         connId: c.nextConnId(),
         connect: c,
         running: true,
         rw: rw,
-        createAt: time.Now(),
+        createAt: now,
+        useAt: now,
+        streams: make(map[int64]*clientStream),
+        pending: make(map[int64]struct{}),
+        done: make(chan struct{}),
+    }
+    if opts.protocolVersion >= ProtocolV2 {
+        conn.heartbeat = newHeartbeat(opts.heartbeatInterval, opts.heartbeatTimeout)
     }
+    conn.writer = newConnWriter(rw, 0, func(error) {
+        rw.Close()
+    })
     go conn.recv(rw)
+    if conn.heartbeat != nil {
+        go conn.keepalive()
+    }
     return conn
 }
 
-func (conn *clientConn)reconnect() error {
-    conn.mu.Lock()
-    if conn.running && !conn.expired() {
-        conn.mu.Unlock()
-        return nil
+// keepalive pings the server when the connection has been quiet and closes it when the server
+// does not answer, a half-open connection would otherwise hold its calls until they time out.
+func (conn *clientConn)keepalive() {
+    ticker := time.NewTicker(conn.heartbeat.tick())
+    defer ticker.Stop()
+    for {
+        select {
+        case now := <- ticker.C:
+            ping, dead := conn.heartbeat.check(now)
+            if dead {
+                conn.close()
+                return
+            }
+            if ping {
+                conn.write(conn.connect.client.packFrame(msgTypePing, nil))
+            }
+        case <- conn.done:
+            return
+        }
     }
-    rw, err := net.Dial("tcp", conn.connect.addr)
-    if err != nil {
+}
+
+// goAway takes the connection out of the pool so it only waits for pending responses, it is
+// closed once they arrived. The endpoint drains as long as such a connection is open.
+func (conn *clientConn)goAway() {
+    conn.mu.Lock()
+    if conn.closed() || conn.draining {
         conn.mu.Unlock()
-        return err
+        return
     }
-    conn.rw = rw
-    conn.callNum = 0
-    conn.running = true
-    conn.createAt = time.Now()
+    conn.draining = true
+    drained := conn.drained()
     conn.mu.Unlock()
-    go conn.recv(rw)
-    return nil
+    atomic.AddInt32(&conn.connect.draining, 1)
+    conn.connect.removeConn(conn.connId)
+    if drained {
+        conn.close()
+    }
 }
 
-func (conn *clientConn)expired() bool {
-    if conn.connect.client.opts.maxIdleTime > 0 {
-        return time.Now().Sub(conn.createAt) >= conn.connect.client.opts.maxIdleTime
-    }
-    return false
+// drained reports whether a draining connection has nothing left to wait for, conn.mu is held.
+func (conn *clientConn)drained() bool {
+    return conn.draining && len(conn.pending) == 0 && len(conn.streams) == 0
 }
 
 func (conn *clientConn)close() {
@@ -486,9 +1135,69 @@ func (conn *clientConn)close() {
         return
     }
     conn.running = false
+    if conn.draining {
+        atomic.AddInt32(&conn.connect.draining, -1)
+    }
+    close(conn.done)
+    rw, writer := conn.rw, conn.writer
+    streams := conn.streams
+    conn.streams = make(map[int64]*clientStream)
+    ids := make([]int64, 0, len(conn.pending))
+    for id := range conn.pending {
+        ids = append(ids, id)
+    }
+    conn.pending = make(map[int64]struct{})
     conn.mu.Unlock()
-    conn.rw.Close()
+    writer.close()
+    rw.Close()
     conn.connect.removeConn(conn.connId)
+    // the responses can not arrive anymore, the calls fail now instead of at their timeout.
+    conn.connect.client.failPending(ErrConnClosed, ids)
+    for _, stream := range streams {
+        stream.end(ErrStreamClosed)
+    }
+}
+
+func (conn *clientConn)addPending(id int64) error {
+    conn.mu.Lock()
+    defer conn.mu.Unlock()
+    if conn.closed() {
+        return ErrConnClosed
+    }
+    conn.pending[id] = struct{}{}
+    conn.useAt = time.Now()
+    return nil
+}
+
+func (conn *clientConn)removePending(id int64) {
+    conn.mu.Lock()
+    delete(conn.pending, id)
+    drained := conn.drained()
+    conn.mu.Unlock()
+    if drained {
+        conn.close()
+    }
+}
+
+func (conn *clientConn)addStream(stream *clientStream) bool {
+    conn.mu.Lock()
+    defer conn.mu.Unlock()
+    if conn.closed() {
+        return false
+    }
+    conn.streams[stream.id] = stream
+    conn.useAt = time.Now()
+    return true
+}
+
+func (conn *clientConn)removeStream(id int64) {
+    conn.mu.Lock()
+    delete(conn.streams, id)
+    drained := conn.drained()
+    conn.mu.Unlock()
+    if drained {
+        conn.close()
+    }
 }
 
 func (conn *clientConn)closed() bool {
@@ -505,19 +1214,17 @@ func (conn *clientConn)recv(rw net.Conn) {
     )
 
     for {
-        if conn.closed() {
-            return
-        }
         n, err := rw.Read(readBuf)
         if err != nil {
             return
         }
+        conn.heartbeat.received()
         buf = append(buf, readBuf[:n]...)
         for {
-            body, n, state := readBody(buf)
+            f, n, state := readFrame(buf)
             if state == state_full {
                 buf = buf[n:]
-                conn.invoke(body)
+                conn.dispatch(f)
                 continue
             }
             if state == state_need_read {
@@ -528,12 +1235,39 @@ func (conn *clientConn)recv(rw net.Conn) {
     }
 }
 
+func (conn *clientConn)dispatch(f *frame) {
+    if f.version < ProtocolV2 || f.msgType == msgTypeResponse {
+        conn.invoke(f.payload)
+        return
+    }
+    switch f.msgType {
+    case msgTypeGoAway:
+        conn.goAway()
+    case msgTypePing:
+        conn.write(conn.connect.client.packFrame(msgTypePong, nil))
+    }
+}
+
 func (conn *clientConn)invoke(body []byte) {
     protocol := conn.connect.client.protocol
     req, err := protocol.UnPacketResponse(body)
     if err != nil {
         return
     }
+    if req.RequestId == 0 && req.Meta[goAwayKey] != "" {
+        conn.goAway()
+        return
+    }
+    if req.StreamFrame != StreamFrame_STREAM_NONE {
+        conn.mu.Lock()
+        stream, ok := conn.streams[req.RequestId]
+        conn.mu.Unlock()
+        if ok {
+            stream.handle(req)
+        }
+        return
+    }
+    conn.removePending(req.RequestId)
     conn.connect.client.rwLock.Lock()
     respChan, ok := conn.connect.client.reqMap[req.RequestId]
     if ok {
@@ -543,11 +1277,43 @@ func (conn *clientConn)invoke(body []byte) {
     conn.connect.client.rwLock.Unlock()
 }
 
-func (conn *clientConn)send(pkg []byte) error {
-    if err := conn.reconnect(); err != nil {
+func (conn *clientConn)write(pkg []byte) error {
+    writer, err := conn.openWriter()
+    if err != nil {
         return err
     }
-    _, err := conn.rw.Write(pkg)
-    conn.useAt = time.Now()
-    return err
+    return writer.write(pkg)
 }
+
+// writeWait is write returning once pkg is on the wire, its error tells the server did not get it.
+func (conn *clientConn)writeWait(pkg []byte) error {
+    writer, err := conn.openWriter()
+    if err != nil {
+        return err
+    }
+    return writer.writeWait(pkg)
+}
+
+func (conn *clientConn)openWriter() (*connWriter, error) {
+    conn.mu.Lock()
+    defer conn.mu.Unlock()
+    if conn.closed() {
+        return nil, ErrConnClosed
+    }
+    return conn.writer, nil
+}
+
+// cancel tells the server the caller gave up on the request, so the handler's context is done.
+// v1 servers have no cancel frame.
+func (conn *clientConn)cancel(id int64) {
+    client := conn.connect.client
+    if client.opts.protocolVersion < ProtocolV2 {
+        return
+    }
+    bs, err := client.protocol.PacketRequest(&Request{RequestId: id})
+    if err != nil {
+        return
+    }
+    conn.write(client.packFrame(msgTypeCancel, bs))
+}
+
@@ -106,9 +106,36 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
     generateClient(g, service)
 }
 
+func isStream(method *protogen.Method) bool {
+    return method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer()
+}
+
+func hasStream(service *protogen.Service) bool {
+    for _, method := range service.Methods {
+        if isStream(method) {
+            return true
+        }
+    }
+    return false
+}
+
+// streamSignature returns the parameters of a streaming server method, it gets its typed
+// stream instead of a context, the context is available from stream.Context().
+func streamSignature(service *protogen.Service, method *protogen.Method) string {
+    streamType := service.GoName + "_" + method.GoName + "Server"
+    if !method.Desc.IsStreamingClient() {
+        return "(req *" + method.Input.GoIdent.GoName + ", stream " + streamType + ") error"
+    }
+    return "(stream " + streamType + ") error"
+}
+
 func generateService(g *protogen.GeneratedFile, service *protogen.Service) {
     g.P("type ", service.GoName, "Server interface {")
     for _, method := range service.Methods {
+        if isStream(method) {
+            g.P(method.GoName, streamSignature(service, method))
+            continue
+        }
         g.P(method.GoName, "(", contextPackage.Ident("Context"), ", *", method.Input.GoIdent.GoName, ") (*", method.Output.GoIdent.GoName, ", error)")
     }
     g.P("}")
@@ -117,12 +144,22 @@ func generateService(g *protogen.GeneratedFile, service *protogen.Service) {
     g.P("type Nop", service.GoName, "ServerImpl struct{}")
     g.P()
     for _, method := range service.Methods {
+        if isStream(method) {
+            g.P("func (_ *Nop", service.GoName, "ServerImpl)", method.GoName, streamSignature(service, method), " {")
+            g.P("return ", fmtPath.Ident("Errorf"), "(\"method ", method.GoName, " not implemented\")")
+            g.P("}")
+            g.P()
+            continue
+        }
         g.P("func (_ *Nop", service.GoName, "ServerImpl)", method.GoName, "(ctx ", contextPackage.Ident("Context"), ", req *", method.Input.GoIdent.GoName, ") (*", method.Output.GoIdent.GoName, ", error) {")
         g.P("return nil, ", fmtPath.Ident("Errorf"), "(\"method ", method.GoName, " not implemented\")")
         g.P("}")
         g.P()
     }
     g.P("func New", service.GoName, "Server(name string, impl ", service.GoName, "Server, opts ...", wrpcgoPackage.Ident("ServerOption") ,") ", wrpcgoPackage.Ident("Server"), " {")
+    if hasStream(service) {
+        g.P("opts = append([]", wrpcgoPackage.Ident("ServerOption"), "{", wrpcgoPackage.Ident("WithServerOptionStreamDispatcher"), "(", service.GoName, "ServerStreamDispatcher)}, opts...)")
+    }
     g.P("return ", wrpcgoPackage.Ident("NewRPCServer"), "(name, impl, ", service.GoName, "ServerDispatcher, opts...)")
     g.P("}")
     g.P()
@@ -137,6 +174,9 @@ func generateDispatcher(g *protogen.GeneratedFile, service *protogen.Service) {
     g.P("_ = obj")
     g.P("switch req.Method {")
     for _, method := range service.Methods {
+        if isStream(method) {
+            continue
+        }
         g.P("case \"", method.GoName, "\":")
         g.P("input := ", method.Input.GoIdent.GoName, "{}")
         g.P("if err := enc.Decode(req.Body, &input); err != nil {")
@@ -157,6 +197,85 @@ func generateDispatcher(g *protogen.GeneratedFile, service *protogen.Service) {
     g.P("}")
     g.P("}")
     g.P()
+
+    if hasStream(service) {
+        generateStreamDispatcher(g, service)
+    }
+}
+
+func generateStreamDispatcher(g *protogen.GeneratedFile, service *protogen.Service) {
+    g.P("func ", service.GoName, "ServerStreamDispatcher(impl interface{}, method string, stream ", wrpcgoPackage.Ident("ServerStream"), ") error {")
+    g.P("obj, ok := impl.(", service.GoName, "Server)")
+    g.P("if !ok {")
+    g.P("return ", fmtPath.Ident("Errorf"), "(\"method %s not found\", method)")
+    g.P("}")
+    g.P("switch method {")
+    for _, method := range service.Methods {
+        if !isStream(method) {
+            continue
+        }
+        streamImpl := lowerFirstLatter(service.GoName) + method.GoName + "Server"
+        g.P("case \"", method.GoName, "\":")
+        if !method.Desc.IsStreamingClient() {
+            g.P("input := &", method.Input.GoIdent.GoName, "{}")
+            g.P("if err := stream.RecvMsg(input); err != nil {")
+            g.P("return err")
+            g.P("}")
+            g.P("return obj.", method.GoName, "(input, &", streamImpl, "{stream})")
+        } else {
+            g.P("return obj.", method.GoName, "(&", streamImpl, "{stream})")
+        }
+    }
+    g.P("default:")
+    g.P("return ", fmtPath.Ident("Errorf"), "(\"method %s not found\", method)")
+    g.P("}")
+    g.P("}")
+    g.P()
+
+    for _, method := range service.Methods {
+        if isStream(method) {
+            generateServerStream(g, service, method)
+        }
+    }
+}
+
+func generateServerStream(g *protogen.GeneratedFile, service *protogen.Service, method *protogen.Method) {
+    streamType := service.GoName + "_" + method.GoName + "Server"
+    streamImpl := lowerFirstLatter(service.GoName) + method.GoName + "Server"
+    g.P("type ", streamType, " interface {")
+    if method.Desc.IsStreamingServer() {
+        g.P("Send(*", method.Output.GoIdent.GoName, ") error")
+    } else {
+        g.P("SendAndClose(*", method.Output.GoIdent.GoName, ") error")
+    }
+    if method.Desc.IsStreamingClient() {
+        g.P("Recv() (*", method.Input.GoIdent.GoName, ", error)")
+    }
+    g.P(wrpcgoPackage.Ident("ServerStream"))
+    g.P("}")
+    g.P()
+    g.P("type ", streamImpl, " struct {")
+    g.P(wrpcgoPackage.Ident("ServerStream"))
+    g.P("}")
+    g.P()
+    if method.Desc.IsStreamingServer() {
+        g.P("func (x *", streamImpl, ")Send(m *", method.Output.GoIdent.GoName, ") error {")
+    } else {
+        g.P("func (x *", streamImpl, ")SendAndClose(m *", method.Output.GoIdent.GoName, ") error {")
+    }
+    g.P("return x.ServerStream.SendMsg(m)")
+    g.P("}")
+    g.P()
+    if method.Desc.IsStreamingClient() {
+        g.P("func (x *", streamImpl, ")Recv() (*", method.Input.GoIdent.GoName, ", error) {")
+        g.P("m := &", method.Input.GoIdent.GoName, "{}")
+        g.P("if err := x.ServerStream.RecvMsg(m); err != nil {")
+        g.P("return nil, err")
+        g.P("}")
+        g.P("return m, nil")
+        g.P("}")
+        g.P()
+    }
 }
 
 func generateClient(g *protogen.GeneratedFile, service *protogen.Service) {
@@ -172,6 +291,10 @@ func generateClient(g *protogen.GeneratedFile, service *protogen.Service) {
     g.P()
 
     for _, method := range service.Methods {
+        if isStream(method) {
+            generateClientStream(g, service, method)
+            continue
+        }
         g.P("func (client *", serviceName, "Client)", method.GoName, "(ctx ", contextPackage.Ident("Context"),
             ", req *", method.Input.GoIdent.GoName, ", opts ...map[string]string) (*", method.Output.GoIdent.GoName, ", error) {")
         g.P("bin, err := ", protoPackage.Ident("Marshal"), "(req)")
@@ -208,6 +331,15 @@ func generateClient(g *protogen.GeneratedFile, service *protogen.Service) {
         g.P()
         g.P("func (client *", serviceName, "Client)Broadcast", method.GoName, "(ctx ", contextPackage.Ident("Context"),
             ", req *", method.Input.GoIdent.GoName, ", opts ...map[string]string) (map[string]*", method.Output.GoIdent.GoName, ", map[string]error) {")
+        g.P("options := make([]", wrpcgoPackage.Ident("BroadcastOption"), ", 0, len(opts))")
+        g.P("for _, meta := range opts {")
+        g.P("options = append(options, ", wrpcgoPackage.Ident("WithBroadcastOptionMeta"), "(meta))")
+        g.P("}")
+        g.P("return client.Broadcast", method.GoName, "WithOptions(ctx, req, options...)")
+        g.P("}")
+        g.P()
+        g.P("func (client *", serviceName, "Client)Broadcast", method.GoName, "WithOptions(ctx ", contextPackage.Ident("Context"),
+            ", req *", method.Input.GoIdent.GoName, ", opts ...", wrpcgoPackage.Ident("BroadcastOption"), ") (map[string]*", method.Output.GoIdent.GoName, ", map[string]error) {")
         g.P("var resps = make(map[string]*", method.Output.GoIdent.GoName, ")")
         g.P("var errs = make(map[string]error)")
         g.P("bin, err := ", protoPackage.Ident("Marshal"), "(req)")
@@ -215,22 +347,126 @@ func generateClient(g *protogen.GeneratedFile, service *protogen.Service) {
         g.P("errs[\"marshalErr\"] = err")
         g.P("return nil, errs")
         g.P("}")
-        g.P("addrs := client.client.GetAllEndpoints()")
-        g.P("for _, addr := range addrs {")
-        g.P("bs, err :=  client.client.Invoke(ctx, \"proto\", addr, \"", method.GoName, "\", bin, opts...)")
+        g.P("results, err := client.client.Broadcast(ctx, \"proto\", \"", method.GoName, "\", bin, opts...)")
         g.P("if err != nil {")
-        g.P("errs[addr] = err")
-        g.P("} else {")
+        g.P("errs[\"broadcastErr\"] = err")
+        g.P("}")
+        g.P("for _, result := range results {")
+        g.P("if result.Err != nil {")
+        g.P("errs[result.Addr] = result.Err")
+        g.P("continue")
+        g.P("}")
         g.P("resp := &", method.Output.GoIdent.GoName, "{}")
-        g.P("if err := ", protoPackage.Ident("Unmarshal") ,"(bs, resp); err != nil {")
-        g.P("errs[addr] = err")
+        g.P("if err := ", protoPackage.Ident("Unmarshal") ,"(result.Body, resp); err != nil {")
+        g.P("errs[result.Addr] = err")
         g.P("} else {")
-        g.P("resps[addr] = resp")
-        g.P("}")
+        g.P("resps[result.Addr] = resp")
         g.P("}")
         g.P("}")
         g.P("return resps, errs")
         g.P("}")
         g.P()
+        generateClientAsync(g, service, method)
+    }
+}
+
+func generateClientAsync(g *protogen.GeneratedFile, service *protogen.Service, method *protogen.Method) {
+    serviceName := upperFirstLatter(service.GoName)
+    callType := service.GoName + "_" + method.GoName + "Call"
+    g.P("type ", callType, " struct {")
+    g.P("call *", wrpcgoPackage.Ident("Call"))
+    g.P("}")
+    g.P()
+    g.P("func (c *", callType, ")Done() <-chan struct{} {")
+    g.P("return c.call.Done()")
+    g.P("}")
+    g.P()
+    g.P("func (c *", callType, ")Result() (*", method.Output.GoIdent.GoName, ", error) {")
+    g.P("bs, err := c.call.Result()")
+    g.P("if err != nil {")
+    g.P("return nil, err")
+    g.P("}")
+    g.P("resp := &", method.Output.GoIdent.GoName, "{}")
+    g.P("if err := ", protoPackage.Ident("Unmarshal") ,"(bs, resp); err != nil {")
+    g.P("return nil, err")
+    g.P("}")
+    g.P("return resp, nil")
+    g.P("}")
+    g.P()
+    g.P("func (client *", serviceName, "Client)", method.GoName, "Async(ctx ", contextPackage.Ident("Context"),
+        ", req *", method.Input.GoIdent.GoName, ", opts ...map[string]string) *", callType, " {")
+    g.P("bin, err := ", protoPackage.Ident("Marshal"), "(req)")
+    g.P("if err != nil {")
+    g.P("return &", callType, "{call: ", wrpcgoPackage.Ident("NewErrorCall"), "(err)}")
+    g.P("}")
+    g.P("return &", callType, "{call: client.client.Go(ctx, \"proto\", \"\", \"", method.GoName, "\", bin, opts...)}")
+    g.P("}")
+    g.P()
+}
+
+func generateClientStream(g *protogen.GeneratedFile, service *protogen.Service, method *protogen.Method) {
+    serviceName := upperFirstLatter(service.GoName)
+    streamType := service.GoName + "_" + method.GoName + "Client"
+    streamImpl := lowerFirstLatter(service.GoName) + method.GoName + "Client"
+    if method.Desc.IsStreamingClient() {
+        g.P("func (client *", serviceName, "Client)", method.GoName, "(ctx ", contextPackage.Ident("Context"),
+            ", opts ...map[string]string) (", streamType, ", error) {")
+    } else {
+        g.P("func (client *", serviceName, "Client)", method.GoName, "(ctx ", contextPackage.Ident("Context"),
+            ", req *", method.Input.GoIdent.GoName, ", opts ...map[string]string) (", streamType, ", error) {")
+    }
+    g.P("stream, err := client.client.NewStream(ctx, \"proto\", \"\", \"", method.GoName, "\", opts...)")
+    g.P("if err != nil {")
+    g.P("return nil, err")
+    g.P("}")
+    g.P("x := &", streamImpl, "{stream}")
+    if !method.Desc.IsStreamingClient() {
+        g.P("if err := x.ClientStream.SendMsg(req); err != nil {")
+        g.P("return nil, err")
+        g.P("}")
+        g.P("if err := x.ClientStream.CloseSend(); err != nil {")
+        g.P("return nil, err")
+        g.P("}")
+    }
+    g.P("return x, nil")
+    g.P("}")
+    g.P()
+
+    g.P("type ", streamType, " interface {")
+    if method.Desc.IsStreamingClient() {
+        g.P("Send(*", method.Input.GoIdent.GoName, ") error")
+    }
+    if method.Desc.IsStreamingServer() {
+        g.P("Recv() (*", method.Output.GoIdent.GoName, ", error)")
+    } else {
+        g.P("CloseAndRecv() (*", method.Output.GoIdent.GoName, ", error)")
+    }
+    g.P(wrpcgoPackage.Ident("ClientStream"))
+    g.P("}")
+    g.P()
+    g.P("type ", streamImpl, " struct {")
+    g.P(wrpcgoPackage.Ident("ClientStream"))
+    g.P("}")
+    g.P()
+    if method.Desc.IsStreamingClient() {
+        g.P("func (x *", streamImpl, ")Send(m *", method.Input.GoIdent.GoName, ") error {")
+        g.P("return x.ClientStream.SendMsg(m)")
+        g.P("}")
+        g.P()
+    }
+    if method.Desc.IsStreamingServer() {
+        g.P("func (x *", streamImpl, ")Recv() (*", method.Output.GoIdent.GoName, ", error) {")
+    } else {
+        g.P("func (x *", streamImpl, ")CloseAndRecv() (*", method.Output.GoIdent.GoName, ", error) {")
+        g.P("if err := x.ClientStream.CloseSend(); err != nil {")
+        g.P("return nil, err")
+        g.P("}")
     }
+    g.P("m := &", method.Output.GoIdent.GoName, "{}")
+    g.P("if err := x.ClientStream.RecvMsg(m); err != nil {")
+    g.P("return nil, err")
+    g.P("}")
+    g.P("return m, nil")
+    g.P("}")
+    g.P()
 }
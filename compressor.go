@@ -0,0 +1,138 @@
+package wrpc_go
+
+import (
+    "bytes"
+    "compress/gzip"
+    "compress/zlib"
+    "io"
+    "io/ioutil"
+
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+)
+
+// Compressor compresses the body of requests and responses. The client names it in the meta
+// of the request, so the server must have one registered under the same name.
+type Compressor interface {
+    Compress(bs []byte) ([]byte, error)
+    // Decompress returns a reader of the decompressed r, the caller bounds how much is read.
+    Decompress(r io.Reader) (io.Reader, error)
+    Name() string
+}
+
+var compMap map[string]Compressor
+
+func init() {
+    compMap = make(map[string]Compressor)
+    RegisterCompressor(&gzipCompressor{})
+    RegisterCompressor(&zlibCompressor{})
+}
+
+func RegisterCompressor(comp Compressor) {
+    if comp == nil || comp.Name() == "" || comp.Name() == CompressNone {
+        return
+    }
+    compMap[comp.Name()] = comp
+}
+
+func GetCompressor(name string) Compressor {
+    if comp, ok := compMap[name]; ok {
+        return comp
+    }
+    return nil
+}
+
+const (
+    CompressGzip = "gzip"
+    CompressZlib = "zlib"
+    // CompressNone set as CompressType in the meta of a call turns compression off for it.
+    CompressNone = "identity"
+)
+
+const (
+    defaultCompressThreshold = 1024
+    defaultMaxMessageSize    = 4 << 20
+)
+
+type gzipCompressor struct{}
+
+func (*gzipCompressor)Compress(bs []byte) ([]byte, error) {
+    var buf bytes.Buffer
+    w := gzip.NewWriter(&buf)
+    if _, err := w.Write(bs); err != nil {
+        return nil, err
+    }
+    if err := w.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+func (*gzipCompressor)Decompress(r io.Reader) (io.Reader, error) {
+    return gzip.NewReader(r)
+}
+
+func (*gzipCompressor)Name() string { return CompressGzip }
+
+type zlibCompressor struct{}
+
+func (*zlibCompressor)Compress(bs []byte) ([]byte, error) {
+    var buf bytes.Buffer
+    w := zlib.NewWriter(&buf)
+    if _, err := w.Write(bs); err != nil {
+        return nil, err
+    }
+    if err := w.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+func (*zlibCompressor)Decompress(r io.Reader) (io.Reader, error) {
+    return zlib.NewReader(r)
+}
+
+func (*zlibCompressor)Name() string { return CompressZlib }
+
+// compress returns bs compressed with name, and "" when it is left as is because no compressor
+// is asked for or bs is smaller than threshold.
+func compress(name string, threshold int, bs []byte) ([]byte, string, error) {
+    if name == "" || name == CompressNone || len(bs) < threshold {
+        return bs, "", nil
+    }
+    comp := GetCompressor(name)
+    if comp == nil {
+        return nil, "", uerror.ErrCompressorNotFound
+    }
+    out, err := comp.Compress(bs)
+    if err != nil {
+        return nil, "", err
+    }
+    return out, name, nil
+}
+
+// decompress returns bs decompressed with name, ErrMessageTooLarge when it would be larger than
+// maxSize, which is not checked when <= 0. A few bytes may decompress to gigabytes.
+func decompress(name string, maxSize int, bs []byte) ([]byte, error) {
+    if name == "" {
+        return bs, nil
+    }
+    comp := GetCompressor(name)
+    if comp == nil {
+        return nil, uerror.ErrCompressorNotFound
+    }
+    r, err := comp.Decompress(bytes.NewReader(bs))
+    if err != nil {
+        return nil, err
+    }
+    if maxSize <= 0 {
+        return ioutil.ReadAll(r)
+    }
+    out, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+    if err != nil {
+        return nil, err
+    }
+    if len(out) > maxSize {
+        return nil, uerror.ErrMessageTooLarge
+    }
+    return out, nil
+}
@@ -0,0 +1,67 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "encoding/json"
+    "strings"
+    "testing"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+)
+
+// twice invokes every call two times, as an interceptor retrying by itself would.
+func twice(ctx context.Context, call *wrpc_go.ClientCall, invoker wrpc_go.ClientInvoker) ([]byte, error) {
+    if _, err := invoker(ctx, call); err != nil {
+        return nil, err
+    }
+    return invoker(ctx, call)
+}
+
+func TestCompressReinvoked(t *testing.T) {
+    ts := startServer(t, wrpc_go.WithServerOptionCompressThreshold(0))
+    client := newClient(t, ts, wrpc_go.WithClientOptionCompressor(wrpc_go.CompressGzip, 0),
+        wrpc_go.WithClientOptionInterceptors(twice))
+
+    body := strings.Repeat("wrpc", 512)
+    out, err := invoke(client, context.Background(), "Echo", body)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if out != body {
+        t.Fatalf("echo returned %d bytes, want %d", len(out), len(body))
+    }
+
+    out, err = invoke(client, context.Background(), "Meta", body, map[string]string{wrpc_go.CompressType: wrpc_go.CompressZlib})
+    if err != nil {
+        t.Fatal(err)
+    }
+    meta := map[string]string{}
+    if err := json.Unmarshal([]byte(out), &meta); err != nil {
+        t.Fatal(err)
+    }
+    if _, ok := meta[wrpc_go.CompressType]; ok {
+        t.Fatalf("override sent to the server: %v", meta)
+    }
+}
+
+func TestDecompressLimit(t *testing.T) {
+    ts := startServer(t, wrpc_go.WithServerOptionCompressThreshold(0), wrpc_go.WithServerOptionMaxMessageSize(64 * 1024))
+    client := newClient(t, ts, wrpc_go.WithClientOptionCompressor(wrpc_go.CompressGzip, 0),
+        wrpc_go.WithClientOptionMaxMessageSize(16 * 1024))
+
+    // a megabyte of zeros compresses to about a kilobyte.
+    big := `"` + strings.Repeat("0", 1 << 20) + `"`
+    if _, err := invoke(client, context.Background(), "Echo", big); uerror.ParseError(err).Code != 413 {
+        t.Fatalf("request: got %v, want message too large", err)
+    }
+    if calls := ts.Calls("Echo"); calls != 0 {
+        t.Fatalf("handler ran %d times", calls)
+    }
+
+    // the server accepts 32KB, the client does not.
+    body := `"` + strings.Repeat("0", 32 * 1024) + `"`
+    if _, err := invoke(client, context.Background(), "Echo", body); uerror.ParseError(err).Code != 413 {
+        t.Fatalf("response: got %v, want message too large", err)
+    }
+}
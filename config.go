@@ -2,6 +2,7 @@ package wrpc_go
 
 import (
     "flag"
+    "fmt"
     "github.com/wukong-cloud/wrpc-go/internal/discovery"
     "github.com/wukong-cloud/wrpc-go/internal/register"
     "github.com/wukong-cloud/wrpc-go/util/logx"
@@ -19,36 +20,88 @@ type Config struct {
     RegisterConfig *register.RegisterConfig `yaml:"register"`
     ServerConfigs []*ServerConfig `yaml:"server-config"`
     ClientConfig  *ClientConfig   `yaml:"client-config"`
+    ShutdownGrace   time.Duration `yaml:"shutdown-grace"`
+    ShutdownTimeout time.Duration `yaml:"shutdown-timeout"`
 }
 
 type ServerConfig struct {
     Name           string `yaml:"name"`
     IP             string `yaml:"ip"`
     Port           string `yaml:"port"`
+    // Addr overrides ip and port as the address to listen on, e.g. "unix:///var/run/app.sock".
+    Addr           string `yaml:"addr"`
     MaxInvoke      int32  `yaml:"max-invoke"`
     ReadBufferSize int32  `yaml:"read-buffer-size"`
+    InvokeTimeout  time.Duration `yaml:"invoke-timeout"`
+    HeartbeatInterval time.Duration `yaml:"heartbeat-interval"`
+    HeartbeatTimeout  time.Duration `yaml:"heartbeat-timeout"`
+    IdleTimeout    time.Duration `yaml:"idle-timeout"`
+    CompressThreshold int        `yaml:"compress-threshold"`
+    MaxMessageSize int           `yaml:"max-message-size"`
+    TLS            *TLSConfig    `yaml:"tls"`
 }
 
 type ClientConfig struct {
     RequestTimeout time.Duration `yaml:"request-timeout"`
     ReadBufferSize int32         `yaml:"read-buffer-size"`
     Thread         int           `yaml:"thread"`
+    MinConn        int           `yaml:"min-conn"`
     MaxIdleTime    time.Duration `yaml:"max-idle-time"`
+    ConnectTimeout time.Duration `yaml:"connect-timeout"`
     EncodeType     string        `yaml:"encode-type"`
     ReTry          int           `yaml:"retry"`
+    // ProtocolVersion is 1 unless set to 2 once every server is upgraded, v1 servers never
+    // answer v2 frames.
+    ProtocolVersion uint8        `yaml:"protocol-version"`
+    Checksum       bool          `yaml:"checksum"`
+    Balancer       string        `yaml:"balancer"`
+    Breaker        *BreakerConfig `yaml:"breaker"`
+    OutlierDetection *OutlierConfig `yaml:"outlier-detection"`
+    RetryPolicy    *RetryPolicy  `yaml:"retry-policy"`
+    HedgePolicy    map[string]*HedgePolicy `yaml:"hedge-policy"`
+    HeartbeatInterval time.Duration `yaml:"heartbeat-interval"`
+    HeartbeatTimeout  time.Duration `yaml:"heartbeat-timeout"`
+    Compress       string        `yaml:"compress"`
+    CompressThreshold int        `yaml:"compress-threshold"`
+    MaxMessageSize int           `yaml:"max-message-size"`
+    TLS            *TLSConfig    `yaml:"tls"`
 }
 
 var (
     _cfg *Config = nil
 
-    initOnce sync.Once
+    cfgMu sync.Mutex
 )
 
 func GetConfig() *Config {
-    initOnce.Do(initConfig)
+    cfgMu.Lock()
+    defer cfgMu.Unlock()
+    if _cfg == nil {
+        initConfig()
+    }
     return _cfg
 }
 
+// ErrConfigLoaded is returned by LoadConfig once the config is in use.
+var ErrConfigLoaded = fmt.Errorf("config already loaded")
+
+// LoadConfig uses data as the content of the config file, so no file is read. It must be called
+// before anything reads the config, e.g. first in main or TestMain. Empty data gives the defaults.
+// Data that does not parse leaves the config unloaded, LoadConfig can be called again.
+func LoadConfig(data []byte) error {
+    cfgMu.Lock()
+    defer cfgMu.Unlock()
+    if _cfg != nil {
+        return ErrConfigLoaded
+    }
+    cfg, err := parseConfig(data)
+    if err != nil {
+        return err
+    }
+    _cfg = cfg
+    return nil
+}
+
 func GetServerConfig(name string) *ServerConfig {
     cfg := GetConfig()
     for _, c := range cfg.ServerConfigs {
@@ -81,26 +134,84 @@ func initConfig() {
     if err != nil {
         panic(err)
     }
+    cfg, err := parseConfig(data)
+    if err != nil {
+        panic(err)
+    }
+    logx.Log(logx.Kv("config", cfg))
+    _cfg = cfg
+}
+
+func parseConfig(data []byte) (*Config, error) {
     cfg := &Config{
         ClientConfig: defaultClientConfig(),
+        ShutdownGrace: 2000,
+        ShutdownTimeout: 30000,
     }
     if err := yaml.Unmarshal(data, &cfg); err != nil {
-        panic(err)
+        return nil, err
+    }
+    if cfg.ClientConfig == nil {
+        cfg.ClientConfig = defaultClientConfig()
     }
 
     for i := range cfg.ServerConfigs {
-        if cfg.ServerConfigs[i].MaxInvoke <= 0 {
-            cfg.ServerConfigs[i].MaxInvoke = defaultMaxInvoke
-        }
-        if cfg.ServerConfigs[i].ReadBufferSize <= 0 {
-            cfg.ServerConfigs[i].ReadBufferSize = defaultReadBufSize
-        }
+        initServerConfig(cfg.ServerConfigs[i])
     }
 
     cfg.ClientConfig.RequestTimeout = parseTimeout(int32(cfg.ClientConfig.RequestTimeout))
+    cfg.ClientConfig.MaxIdleTime = parseTimeout(int32(cfg.ClientConfig.MaxIdleTime))
+    cfg.ClientConfig.ConnectTimeout = parseTimeout(int32(cfg.ClientConfig.ConnectTimeout))
+    cfg.ClientConfig.HeartbeatInterval = parseTimeout(int32(cfg.ClientConfig.HeartbeatInterval))
+    cfg.ClientConfig.HeartbeatTimeout = parseTimeout(int32(cfg.ClientConfig.HeartbeatTimeout))
+    if cfg.ClientConfig.Breaker != nil {
+        cfg.ClientConfig.Breaker.Window = parseTimeout(int32(cfg.ClientConfig.Breaker.Window))
+        cfg.ClientConfig.Breaker.OpenTimeout = parseTimeout(int32(cfg.ClientConfig.Breaker.OpenTimeout))
+    }
+    if retry := cfg.ClientConfig.RetryPolicy; retry != nil {
+        retry.PerAttemptTimeout = parseTimeout(int32(retry.PerAttemptTimeout))
+        retry.InitialBackoff = parseTimeout(int32(retry.InitialBackoff))
+        retry.MaxBackoff = parseTimeout(int32(retry.MaxBackoff))
+    }
+    for _, hedge := range cfg.ClientConfig.HedgePolicy {
+        if hedge != nil {
+            hedge.Delay = parseTimeout(int32(hedge.Delay))
+        }
+    }
+    if outlier := cfg.ClientConfig.OutlierDetection; outlier != nil {
+        outlier.Interval = parseTimeout(int32(outlier.Interval))
+        outlier.BaseEjectionTime = parseTimeout(int32(outlier.BaseEjectionTime))
+        outlier.MaxEjectionTime = parseTimeout(int32(outlier.MaxEjectionTime))
+    }
+    cfg.ShutdownGrace = parseTimeout(int32(cfg.ShutdownGrace))
+    cfg.ShutdownTimeout = parseTimeout(int32(cfg.ShutdownTimeout))
+    return cfg, nil
+}
 
-    logx.Log(logx.Kv("config", cfg))
-    _cfg = cfg
+// initServerConfig fills the defaults and turns the timeouts, given in milliseconds, into durations.
+func initServerConfig(c *ServerConfig) {
+    if c.MaxInvoke <= 0 {
+        c.MaxInvoke = defaultMaxInvoke
+    }
+    if c.ReadBufferSize <= 0 {
+        c.ReadBufferSize = defaultReadBufSize
+    }
+    c.InvokeTimeout = parseTimeout(int32(c.InvokeTimeout))
+    if c.CompressThreshold <= 0 {
+        c.CompressThreshold = defaultCompressThreshold
+    }
+    if c.MaxMessageSize <= 0 {
+        c.MaxMessageSize = defaultMaxMessageSize
+    }
+    if c.HeartbeatInterval == 0 {
+        c.HeartbeatInterval = defaultHeartbeatInterval
+    }
+    if c.HeartbeatTimeout == 0 {
+        c.HeartbeatTimeout = defaultHeartbeatTimeout
+    }
+    c.HeartbeatInterval = parseTimeout(int32(c.HeartbeatInterval))
+    c.HeartbeatTimeout = parseTimeout(int32(c.HeartbeatTimeout))
+    c.IdleTimeout = parseTimeout(int32(c.IdleTimeout))
 }
 
 func defaultClientConfig() *ClientConfig {
@@ -108,9 +219,19 @@ func defaultClientConfig() *ClientConfig {
         RequestTimeout: 60000,
         ReadBufferSize: defaultReadBufSize,
         MaxIdleTime:    7200000,
+        ConnectTimeout: 3000,
         Thread:         1,
         EncodeType:     "json",
         ReTry:          1,
+        ProtocolVersion: ProtocolV1,
+        Balancer:       BalancerRoundRobin,
+        Breaker:        defaultBreakerConfig(),
+        OutlierDetection: defaultOutlierConfig(),
+        RetryPolicy:    defaultRetryPolicy(),
+        HeartbeatInterval: defaultHeartbeatInterval,
+        HeartbeatTimeout: defaultHeartbeatTimeout,
+        CompressThreshold: defaultCompressThreshold,
+        MaxMessageSize: defaultMaxMessageSize,
     }
 }
 
@@ -0,0 +1,34 @@
+package wrpc_go_test
+
+import (
+    "os"
+    "os/exec"
+    "testing"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+)
+
+// TestLoadConfigError runs in its own process, the other tests load the config first.
+func TestLoadConfigError(t *testing.T) {
+    if os.Getenv("WRPC_LOAD_CONFIG_TEST") != "1" {
+        cmd := exec.Command(os.Args[0], "-test.run=^TestLoadConfigError$")
+        cmd.Env = append(os.Environ(), "WRPC_LOAD_CONFIG_TEST=1")
+        if out, err := cmd.CombinedOutput(); err != nil {
+            t.Fatalf("%v\n%s", err, out)
+        }
+        return
+    }
+
+    if err := wrpc_go.LoadConfig([]byte("client-config: [")); err == nil {
+        t.Fatal("expected a parse error")
+    }
+    if err := wrpc_go.LoadConfig(nil); err != nil {
+        t.Fatalf("load after a parse error: %v", err)
+    }
+    if wrpc_go.GetServerConfig("NoSuchServer") != nil || wrpc_go.GetClientConfig() == nil {
+        t.Fatal("defaults are not loaded")
+    }
+    if err := wrpc_go.LoadConfig(nil); err != wrpc_go.ErrConfigLoaded {
+        t.Fatalf("got %v, want ErrConfigLoaded", err)
+    }
+}
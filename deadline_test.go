@@ -0,0 +1,46 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+)
+
+// handlerDeadline calls Echo with timeout on a server capped by invokeTimeout and returns how
+// long the handler had left.
+func handlerDeadline(t *testing.T, timeout, invokeTimeout time.Duration) time.Duration {
+    ctxs := make(chan context.Context, 1)
+    ts := startServer(t, wrpc_go.WithServerOptionInvokeTimeout(invokeTimeout), handlerContext("Echo", ctxs))
+    client := newClient(t, ts)
+
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+    if _, err := invoke(client, ctx, "Echo", `"a"`); err != nil {
+        t.Fatal(err)
+    }
+    deadline, ok := (<- ctxs).Deadline()
+    if !ok {
+        t.Fatal("the handler has no deadline")
+    }
+    return time.Until(deadline)
+}
+
+func TestHandlerDeadline(t *testing.T) {
+    for _, c := range []struct {
+        name                   string
+        timeout, invokeTimeout time.Duration
+        want                   time.Duration
+    }{
+        {"client", 500 * time.Millisecond, 5 * time.Second, 500 * time.Millisecond},
+        {"server", 5 * time.Second, 500 * time.Millisecond, 500 * time.Millisecond},
+    } {
+        t.Run(c.name, func(t *testing.T) {
+            left := handlerDeadline(t, c.timeout, c.invokeTimeout)
+            if left > c.want || left < c.want - 300 * time.Millisecond {
+                t.Fatalf("handler had %v left, want about %v", left, c.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,204 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "net"
+    "sync"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/wrpctest"
+)
+
+func TestDrainAndRestart(t *testing.T) {
+    ts1, ts2 := startServer(t), startServer(t)
+    client := newClient(t, ts1,
+        wrpc_go.WithClientOptionAddr(ts1.Addr+";"+ts2.Addr),
+        wrpc_go.WithClientOptionDialer(dialer(ts1, ts2)))
+    for i := 0; i < 4; i++ {
+        if _, err := invoke(client, context.Background(), "Echo", "a"); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    // the call in flight on ts1 is answered although ts1 stops meanwhile.
+    inflight := make(chan error, 1)
+    go func() {
+        _, err := client.Invoke(context.Background(), "json", ts1.Addr, "Sleep", []byte("100"))
+        inflight <- err
+    }()
+    time.Sleep(20 * time.Millisecond)
+    restarted := make(chan error, 1)
+    go func() {
+        restarted <- ts1.Restart(wrpc_go.NewRPCServer(testServer, nil, dispatch, ts1.ServerOptions()...), time.Second)
+    }()
+    if err := <- inflight; err != nil {
+        t.Fatalf("call in flight during the drain: %v", err)
+    }
+    if err := <- restarted; err != nil {
+        t.Fatal(err)
+    }
+
+    before := ts1.Calls("Echo")
+    for i := 0; i < 10; i++ {
+        if _, err := invoke(client, context.Background(), "Echo", "a"); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if ts1.Calls("Echo") == before {
+        t.Fatal("the restarted endpoint gets no traffic")
+    }
+    for _, status := range client.GetEndpointStatus() {
+        if status.Draining {
+            t.Fatalf("%s still draining", status.Addr)
+        }
+    }
+}
+
+func TestIdleReapDoesNotDrainEndpoint(t *testing.T) {
+    ts1, ts2 := startServer(t, wrpc_go.WithServerOptionIdleTimeout(50 * time.Millisecond)), startServer(t)
+    client := newClient(t, ts1,
+        wrpc_go.WithClientOptionAddr(ts1.Addr+";"+ts2.Addr),
+        wrpc_go.WithClientOptionDialer(dialer(ts1, ts2)))
+    invoke(client, context.Background(), "Echo", "a")
+    invoke(client, context.Background(), "Echo", "a")
+    time.Sleep(200 * time.Millisecond)
+
+    before := ts1.Calls("Echo")
+    for i := 0; i < 4; i++ {
+        if _, err := invoke(client, context.Background(), "Echo", "a"); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if ts1.Calls("Echo") == before {
+        t.Fatal("the reaped endpoint gets no traffic")
+    }
+}
+
+// dialer connects the addresses of the servers to their listeners.
+func dialer(servers ...*wrpctest.Server) wrpc_go.Dialer {
+    return func(ctx context.Context, addr string) (net.Conn, error) {
+        for _, ts := range servers {
+            if ts.Addr == addr {
+                return ts.Listener.Dial(ctx, addr)
+            }
+        }
+        return nil, wrpctest.ErrConnectionRefused
+    }
+}
+
+func TestPoolDoesNotRedialDrainingEndpoint(t *testing.T) {
+    ts := startServer(t)
+    var mu sync.Mutex
+    dials := 0
+    client := newClient(t, ts,
+        wrpc_go.WithClientOptionMinConn(1),
+        wrpc_go.WithClientOptionMaxIdleTime(40 * time.Millisecond),
+        wrpc_go.WithClientOptionDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+            mu.Lock()
+            dials++
+            mu.Unlock()
+            return ts.Listener.Dial(ctx, addr)
+        }))
+    count := func() int {
+        mu.Lock()
+        defer mu.Unlock()
+        return dials
+    }
+
+    inflight := make(chan error, 1)
+    go func() {
+        _, err := invoke(client, context.Background(), "Sleep", "300")
+        inflight <- err
+    }()
+    time.Sleep(20 * time.Millisecond)
+    restarted := make(chan error, 1)
+    go func() {
+        restarted <- ts.Restart(wrpc_go.NewRPCServer(testServer, nil, dispatch, ts.ServerOptions()...), time.Second)
+    }()
+
+    // the pool is maintained every 20ms, the drain lasts until the call is answered.
+    time.Sleep(50 * time.Millisecond)
+    before := count()
+    time.Sleep(150 * time.Millisecond)
+    if after := count(); after != before {
+        t.Fatalf("the draining endpoint was dialed %d times", after-before)
+    }
+    if err := <- inflight; err != nil {
+        t.Fatal(err)
+    }
+    if err := <- restarted; err != nil {
+        t.Fatal(err)
+    }
+    if _, err := invoke(client, context.Background(), "Echo", "a"); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestStopAnswersRequestsSentMeanwhile(t *testing.T) {
+    // one request is handled at a time, the others wait for a tick while the server stops, and
+    // the client reads the goAway late, so it keeps sending requests after it was sent.
+    ts, other := startServer(t, wrpc_go.WithServerOptionMaxInvoke(1)), startServer(t)
+    client := newClient(t, ts,
+        wrpc_go.WithClientOptionAddr(ts.Addr+";"+other.Addr),
+        wrpc_go.WithClientOptionDialer(slowReads(dialer(ts, other), 20 * time.Millisecond)),
+        // only requests that could not be written are retried, a dial to the stopped server.
+        wrpc_go.WithClientOptionRetryPolicy(&wrpc_go.RetryPolicy{MaxAttempts: 3}))
+    if _, err := invoke(client, context.Background(), "Echo", "a"); err != nil {
+        t.Fatal(err)
+    }
+
+    stop := make(chan struct{})
+    errs := make(chan error, 64)
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                select {
+                case <- stop:
+                    return
+                default:
+                }
+                if _, err := invoke(client, context.Background(), "Sleep", "5"); err != nil {
+                    errs <- err
+                    return
+                }
+            }
+        }()
+    }
+    time.Sleep(30 * time.Millisecond)
+    if err := ts.Restart(wrpc_go.NewRPCServer(testServer, nil, dispatch, ts.ServerOptions(wrpc_go.WithServerOptionMaxInvoke(1))...), 5 * time.Second); err != nil {
+        t.Fatal(err)
+    }
+    time.Sleep(30 * time.Millisecond)
+    close(stop)
+    wg.Wait()
+    close(errs)
+    for err := range errs {
+        t.Fatalf("call during the stop: %v", err)
+    }
+}
+
+// slowReads delays every read of the connections of dial by delay.
+func slowReads(dial wrpc_go.Dialer, delay time.Duration) wrpc_go.Dialer {
+    return func(ctx context.Context, addr string) (net.Conn, error) {
+        conn, err := dial(ctx, addr)
+        if err != nil {
+            return nil, err
+        }
+        return &slowConn{Conn: conn, delay: delay}, nil
+    }
+}
+
+type slowConn struct {
+    net.Conn
+    delay time.Duration
+}
+
+func (c *slowConn)Read(b []byte) (int, error) {
+    time.Sleep(c.delay)
+    return c.Conn.Read(b)
+}
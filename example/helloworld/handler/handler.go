@@ -4,6 +4,7 @@ import (
     "context"
     "fmt"
     "github.com/wukong-cloud/wrpc-go/example/helloworld/protocol/pb"
+    "io"
 )
 
 type HelloServerImpl struct {
@@ -14,3 +15,18 @@ func (this *HelloServerImpl)SayHello(ctx context.Context, req *pb.HelloReq) (*pb
     fmt.Println(">>> say hello to ", req.Name)
     return &pb.HelloResp{Message: "hello " + req.Name}, nil
 }
+
+func (this *HelloServerImpl)SayHelloStream(stream pb.Hello_SayHelloStreamServer) error {
+    for {
+        req, err := stream.Recv()
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+        if err := stream.Send(&pb.HelloResp{Message: "hello " + req.Name}); err != nil {
+            return err
+        }
+    }
+}
@@ -15,6 +15,7 @@ import (
 
 type HelloServer interface {
 	SayHello(context.Context, *HelloReq) (*HelloResp, error)
+	SayHelloStream(stream Hello_SayHelloStreamServer) error
 }
 
 type NopHelloServerImpl struct{}
@@ -23,7 +24,12 @@ func (_ *NopHelloServerImpl) SayHello(ctx context.Context, req *HelloReq) (*Hell
 	return nil, fmt.Errorf("method SayHello not implemented")
 }
 
+func (_ *NopHelloServerImpl) SayHelloStream(stream Hello_SayHelloStreamServer) error {
+	return fmt.Errorf("method SayHelloStream not implemented")
+}
+
 func NewHelloServer(name string, impl HelloServer, opts ...wrpc_go.ServerOption) wrpc_go.Server {
+	opts = append([]wrpc_go.ServerOption{wrpc_go.WithServerOptionStreamDispatcher(HelloServerStreamDispatcher)}, opts...)
 	return wrpc_go.NewRPCServer(name, impl, HelloServerDispatcher, opts...)
 }
 
@@ -53,6 +59,41 @@ func HelloServerDispatcher(ctx context.Context, impl interface{}, req *wrpc_go.R
 	}
 }
 
+func HelloServerStreamDispatcher(impl interface{}, method string, stream wrpc_go.ServerStream) error {
+	obj, ok := impl.(HelloServer)
+	if !ok {
+		return fmt.Errorf("method %s not found", method)
+	}
+	switch method {
+	case "SayHelloStream":
+		return obj.SayHelloStream(&helloSayHelloStreamServer{stream})
+	default:
+		return fmt.Errorf("method %s not found", method)
+	}
+}
+
+type Hello_SayHelloStreamServer interface {
+	Send(*HelloResp) error
+	Recv() (*HelloReq, error)
+	wrpc_go.ServerStream
+}
+
+type helloSayHelloStreamServer struct {
+	wrpc_go.ServerStream
+}
+
+func (x *helloSayHelloStreamServer) Send(m *HelloResp) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *helloSayHelloStreamServer) Recv() (*HelloReq, error) {
+	m := &HelloReq{}
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 type HelloClient struct {
 	client *wrpc_go.Client
 }
@@ -94,6 +135,14 @@ func (client *HelloClient) SayHelloORI(ctx context.Context, addr string, req *He
 }
 
 func (client *HelloClient) BroadcastSayHello(ctx context.Context, req *HelloReq, opts ...map[string]string) (map[string]*HelloResp, map[string]error) {
+	options := make([]wrpc_go.BroadcastOption, 0, len(opts))
+	for _, meta := range opts {
+		options = append(options, wrpc_go.WithBroadcastOptionMeta(meta))
+	}
+	return client.BroadcastSayHelloWithOptions(ctx, req, options...)
+}
+
+func (client *HelloClient) BroadcastSayHelloWithOptions(ctx context.Context, req *HelloReq, opts ...wrpc_go.BroadcastOption) (map[string]*HelloResp, map[string]error) {
 	var resps = make(map[string]*HelloResp)
 	var errs = make(map[string]error)
 	bin, err := proto.Marshal(req)
@@ -101,19 +150,80 @@ func (client *HelloClient) BroadcastSayHello(ctx context.Context, req *HelloReq,
 		errs["marshalErr"] = err
 		return nil, errs
 	}
-	addrs := client.client.GetAllEndpoints()
-	for _, addr := range addrs {
-		bs, err := client.client.Invoke(ctx, "proto", addr, "SayHello", bin, opts...)
-		if err != nil {
-			errs[addr] = err
+	results, err := client.client.Broadcast(ctx, "proto", "SayHello", bin, opts...)
+	if err != nil {
+		errs["broadcastErr"] = err
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			errs[result.Addr] = result.Err
+			continue
+		}
+		resp := &HelloResp{}
+		if err := proto.Unmarshal(result.Body, resp); err != nil {
+			errs[result.Addr] = err
 		} else {
-			resp := &HelloResp{}
-			if err := proto.Unmarshal(bs, resp); err != nil {
-				errs[addr] = err
-			} else {
-				resps[addr] = resp
-			}
+			resps[result.Addr] = resp
 		}
 	}
 	return resps, errs
 }
+
+type Hello_SayHelloCall struct {
+	call *wrpc_go.Call
+}
+
+func (c *Hello_SayHelloCall) Done() <-chan struct{} {
+	return c.call.Done()
+}
+
+func (c *Hello_SayHelloCall) Result() (*HelloResp, error) {
+	bs, err := c.call.Result()
+	if err != nil {
+		return nil, err
+	}
+	resp := &HelloResp{}
+	if err := proto.Unmarshal(bs, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (client *HelloClient) SayHelloAsync(ctx context.Context, req *HelloReq, opts ...map[string]string) *Hello_SayHelloCall {
+	bin, err := proto.Marshal(req)
+	if err != nil {
+		return &Hello_SayHelloCall{call: wrpc_go.NewErrorCall(err)}
+	}
+	return &Hello_SayHelloCall{call: client.client.Go(ctx, "proto", "", "SayHello", bin, opts...)}
+}
+
+func (client *HelloClient) SayHelloStream(ctx context.Context, opts ...map[string]string) (Hello_SayHelloStreamClient, error) {
+	stream, err := client.client.NewStream(ctx, "proto", "", "SayHelloStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &helloSayHelloStreamClient{stream}
+	return x, nil
+}
+
+type Hello_SayHelloStreamClient interface {
+	Send(*HelloReq) error
+	Recv() (*HelloResp, error)
+	wrpc_go.ClientStream
+}
+
+type helloSayHelloStreamClient struct {
+	wrpc_go.ClientStream
+}
+
+func (x *helloSayHelloStreamClient) Send(m *HelloReq) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *helloSayHelloStreamClient) Recv() (*HelloResp, error) {
+	m := &HelloResp{}
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,144 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.12.2
+// source: frame.proto
+
+package wrpc_go
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StreamFrame int32
+
+const (
+	StreamFrame_STREAM_NONE          StreamFrame = 0
+	StreamFrame_STREAM_OPEN          StreamFrame = 1
+	StreamFrame_STREAM_DATA          StreamFrame = 2
+	StreamFrame_STREAM_HALF_CLOSE    StreamFrame = 3
+	StreamFrame_STREAM_RESET         StreamFrame = 4
+	StreamFrame_STREAM_WINDOW_UPDATE StreamFrame = 5
+)
+
+// Enum value maps for StreamFrame.
+var (
+	StreamFrame_name = map[int32]string{
+		0: "STREAM_NONE",
+		1: "STREAM_OPEN",
+		2: "STREAM_DATA",
+		3: "STREAM_HALF_CLOSE",
+		4: "STREAM_RESET",
+		5: "STREAM_WINDOW_UPDATE",
+	}
+	StreamFrame_value = map[string]int32{
+		"STREAM_NONE":          0,
+		"STREAM_OPEN":          1,
+		"STREAM_DATA":          2,
+		"STREAM_HALF_CLOSE":    3,
+		"STREAM_RESET":         4,
+		"STREAM_WINDOW_UPDATE": 5,
+	}
+)
+
+func (x StreamFrame) Enum() *StreamFrame {
+	p := new(StreamFrame)
+	*p = x
+	return p
+}
+
+func (x StreamFrame) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StreamFrame) Descriptor() protoreflect.EnumDescriptor {
+	return file_frame_proto_enumTypes[0].Descriptor()
+}
+
+func (StreamFrame) Type() protoreflect.EnumType {
+	return &file_frame_proto_enumTypes[0]
+}
+
+func (x StreamFrame) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StreamFrame.Descriptor instead.
+func (StreamFrame) EnumDescriptor() ([]byte, []int) {
+	return file_frame_proto_rawDescGZIP(), []int{0}
+}
+
+var File_frame_proto protoreflect.FileDescriptor
+
+var file_frame_proto_rawDesc = []byte{
+	0x0a, 0x0b, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x77,
+	0x72, 0x70, 0x63, 0x5f, 0x67, 0x6f, 0x2a, 0x83, 0x01, 0x0a, 0x0b, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x12, 0x0f, 0x0a, 0x0b, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d,
+	0x5f, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x53, 0x54, 0x52, 0x45, 0x41,
+	0x4d, 0x5f, 0x4f, 0x50, 0x45, 0x4e, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x53, 0x54, 0x52, 0x45,
+	0x41, 0x4d, 0x5f, 0x44, 0x41, 0x54, 0x41, 0x10, 0x02, 0x12, 0x15, 0x0a, 0x11, 0x53, 0x54, 0x52,
+	0x45, 0x41, 0x4d, 0x5f, 0x48, 0x41, 0x4c, 0x46, 0x5f, 0x43, 0x4c, 0x4f, 0x53, 0x45, 0x10, 0x03,
+	0x12, 0x10, 0x0a, 0x0c, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x52, 0x45, 0x53, 0x45, 0x54,
+	0x10, 0x04, 0x12, 0x18, 0x0a, 0x14, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x57, 0x49, 0x4e,
+	0x44, 0x4f, 0x57, 0x5f, 0x55, 0x50, 0x44, 0x41, 0x54, 0x45, 0x10, 0x05, 0x42, 0x0a, 0x5a, 0x08,
+	0x2f, 0x77, 0x72, 0x70, 0x63, 0x5f, 0x67, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_frame_proto_rawDescOnce sync.Once
+	file_frame_proto_rawDescData = file_frame_proto_rawDesc
+)
+
+func file_frame_proto_rawDescGZIP() []byte {
+	file_frame_proto_rawDescOnce.Do(func() {
+		file_frame_proto_rawDescData = protoimpl.X.CompressGZIP(file_frame_proto_rawDescData)
+	})
+	return file_frame_proto_rawDescData
+}
+
+var file_frame_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_frame_proto_goTypes = []interface{}{
+	(StreamFrame)(0), // 0: wrpc_go.StreamFrame
+}
+var file_frame_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_frame_proto_init() }
+func file_frame_proto_init() {
+	if File_frame_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_frame_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   0,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_frame_proto_goTypes,
+		DependencyIndexes: file_frame_proto_depIdxs,
+		EnumInfos:         file_frame_proto_enumTypes,
+	}.Build()
+	File_frame_proto = out.File
+	file_frame_proto_rawDesc = nil
+	file_frame_proto_goTypes = nil
+	file_frame_proto_depIdxs = nil
+}
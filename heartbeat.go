@@ -0,0 +1,72 @@
+package wrpc_go
+
+import (
+    "sync/atomic"
+    "time"
+)
+
+const (
+    defaultHeartbeatInterval = 30000
+    defaultHeartbeatTimeout  = 10000
+    minHeartbeatTick         = 10 * time.Millisecond
+)
+
+// heartbeat tells when a connection needs a ping and when its peer is gone. Any frame received
+// proves the peer alive, a ping is only sent after interval without one, and the peer is dead
+// when nothing arrived within timeout after the ping. Pings need v2 frames on both sides.
+type heartbeat struct {
+    interval time.Duration
+    timeout  time.Duration
+    lastRecv int64
+    pingAt   int64
+}
+
+func newHeartbeat(interval, timeout time.Duration) *heartbeat {
+    if interval <= 0 {
+        return nil
+    }
+    if timeout <= 0 {
+        timeout = interval
+    }
+    return &heartbeat{
+        interval: interval,
+        timeout: timeout,
+        lastRecv: time.Now().UnixNano(),
+    }
+}
+
+func (h *heartbeat)received() {
+    if h != nil {
+        atomic.StoreInt64(&h.lastRecv, time.Now().UnixNano())
+    }
+}
+
+// tick is how often check should be called.
+func (h *heartbeat)tick() time.Duration {
+    tick := h.interval
+    if h.timeout < tick {
+        tick = h.timeout
+    }
+    tick /= 2
+    if tick < minHeartbeatTick {
+        tick = minHeartbeatTick
+    }
+    return tick
+}
+
+// check returns whether a ping must be sent now and whether the peer missed the last one.
+// It is called from one goroutine only.
+func (h *heartbeat)check(now time.Time) (ping bool, dead bool) {
+    lastRecv := atomic.LoadInt64(&h.lastRecv)
+    if h.pingAt != 0 {
+        if lastRecv < h.pingAt {
+            return false, now.UnixNano()-h.pingAt >= int64(h.timeout)
+        }
+        h.pingAt = 0
+    }
+    if now.UnixNano()-lastRecv >= int64(h.interval) {
+        h.pingAt = now.UnixNano()
+        return true, false
+    }
+    return false, false
+}
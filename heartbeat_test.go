@@ -0,0 +1,47 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "io"
+    "io/ioutil"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/wrpctest"
+)
+
+func TestHeartbeatTimeoutClosesConnection(t *testing.T) {
+    wrpc_go.LoadConfig(nil)
+    l := wrpctest.NewListener()
+    defer func() {
+        l.Close()
+        l.DropConnections()
+    }()
+    // the peer reads everything and answers nothing, not even the pings.
+    go func() {
+        for {
+            conn, err := l.Accept()
+            if err != nil {
+                return
+            }
+            go io.Copy(ioutil.Discard, conn)
+        }
+    }()
+    client := wrpc_go.NewClient(testServer,
+        wrpc_go.WithClientOptionAddr("memory"),
+        wrpc_go.WithClientOptionDialer(l.Dial),
+        wrpc_go.WithClientOptionProtocolVersion(wrpc_go.ProtocolV2),
+        wrpc_go.WithClientOptionHeartbeat(20 * time.Millisecond, 50 * time.Millisecond))
+    defer client.Close()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+    defer cancel()
+    start := time.Now()
+    if _, err := client.Invoke(ctx, "json", "", "Echo", []byte(`"a"`)); err == nil {
+        t.Fatal("expected the call to fail")
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("the call failed after %v, the dead connection was not closed", elapsed)
+    }
+}
@@ -0,0 +1,133 @@
+package wrpc_go
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// HedgePolicy sends up to MaxHedges more copies of a call, each to another endpoint, when no
+// response arrived Delay after the previous one was sent. The first success is used and the
+// other copies are canceled. No hedge is sent while the error rate of the client over the last
+// seconds is above MaxErrorRate, hedging would only add load to failing servers, nor once every
+// available endpoint got a copy. A call pinned to an address is never hedged.
+// A method with a hedge policy is not retried.
+type HedgePolicy struct {
+    Delay        time.Duration `yaml:"delay"`
+    MaxHedges    int           `yaml:"max-hedges"`
+    MaxErrorRate float64       `yaml:"max-error-rate"`
+}
+
+func (client *Client)hedge(ctx context.Context, call *ClientCall, policy *HedgePolicy) ([]byte, error) {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    type hedgeResult struct {
+        body []byte
+        addr string
+        err  error
+    }
+    var (
+        addr    = call.Addr
+        tried   = make(map[*connector]bool)
+        results = make(chan hedgeResult, policy.MaxHedges+1)
+        sent    int
+        pending int
+        lastErr error
+    )
+    send := func() {
+        sent++
+        pc, _, err := client.start(ctx, call, addr, tried)
+        if err != nil {
+            lastErr = err
+            return
+        }
+        pending++
+        go func() {
+            body, err := pc.wait(ctx)
+            results <- hedgeResult{body: body, addr: pc.conn.connect.addr, err: err}
+        }()
+    }
+    canHedge := func() bool {
+        if sent > policy.MaxHedges || addr != "" || !client.hasUntried(tried) {
+            return false
+        }
+        return policy.MaxErrorRate <= 0 || client.errorRate.rate() <= policy.MaxErrorRate
+    }
+
+    send()
+    timer := time.NewTimer(policy.Delay)
+    defer timer.Stop()
+    for {
+        if pending == 0 {
+            if !canHedge() {
+                return nil, lastErr
+            }
+            send()
+            continue
+        }
+        select {
+        case r := <- results:
+            pending--
+            // an answer that is not a failure of the endpoint would be the same from any other.
+            if r.err == nil || breakerResult(r.err) != breakerFailure {
+                call.Addr = r.addr
+                return r.body, r.err
+            }
+            lastErr = r.err
+        case <- timer.C:
+            if canHedge() {
+                send()
+            }
+            timer.Reset(policy.Delay)
+        case <- ctx.Done():
+            return nil, contextError(ctx)
+        }
+    }
+}
+
+const errorRateWindow = 10
+
+// errorRate counts the results of the last errorRateWindow seconds.
+type errorRate struct {
+    mu      sync.Mutex
+    buckets [errorRateWindow]struct {
+        second   int64
+        total    int
+        failures int
+    }
+}
+
+func (e *errorRate)record(result int) {
+    if result == breakerIgnore {
+        return
+    }
+    second := time.Now().Unix()
+    e.mu.Lock()
+    b := &e.buckets[second%errorRateWindow]
+    if b.second != second {
+        b.second, b.total, b.failures = second, 0, 0
+    }
+    b.total++
+    if result == breakerFailure {
+        b.failures++
+    }
+    e.mu.Unlock()
+}
+
+func (e *errorRate)rate() float64 {
+    second := time.Now().Unix()
+    var total, failures int
+    e.mu.Lock()
+    for _, b := range e.buckets {
+        if second-b.second < errorRateWindow {
+            total += b.total
+            failures += b.failures
+        }
+    }
+    e.mu.Unlock()
+    if total == 0 {
+        return 0
+    }
+    return float64(failures) / float64(total)
+}
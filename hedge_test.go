@@ -0,0 +1,37 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+)
+
+func TestHedgeEndpoints(t *testing.T) {
+    ts1, ts2 := startServer(t), startServer(t)
+    client := newClient(t, ts1,
+        wrpc_go.WithClientOptionAddr(ts1.Addr+";"+ts2.Addr),
+        wrpc_go.WithClientOptionDialer(dialer(ts1, ts2)),
+        wrpc_go.WithClientOptionHedgePolicy("Echo", &wrpc_go.HedgePolicy{Delay: 10 * time.Millisecond, MaxHedges: 3}))
+    ts1.InjectLatency("Echo", 100 * time.Millisecond)
+    ts2.InjectLatency("Echo", 100 * time.Millisecond)
+
+    if _, err := client.Invoke(context.Background(), "json", ts1.Addr, "Echo", []byte(`"a"`)); err != nil {
+        t.Fatal(err)
+    }
+    if calls1, calls2 := ts1.Calls("Echo"), ts2.Calls("Echo"); calls1 != 1 || calls2 != 0 {
+        t.Fatalf("pinned call was sent %d and %d times, want it not hedged", calls1, calls2)
+    }
+
+    ts1.Clear()
+    ts2.Clear()
+    ts1.InjectLatency("Echo", 100 * time.Millisecond)
+    ts2.InjectLatency("Echo", 100 * time.Millisecond)
+    if _, err := invoke(client, context.Background(), "Echo", `"a"`); err != nil {
+        t.Fatal(err)
+    }
+    if calls1, calls2 := ts1.Calls("Echo"), ts2.Calls("Echo"); calls1 != 1 || calls2 != 1 {
+        t.Fatalf("call was sent %d and %d times, want once to each endpoint", calls1, calls2)
+    }
+}
@@ -0,0 +1,59 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "encoding/json"
+    "strconv"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/wrpctest"
+)
+
+const testServer = "TestServer"
+
+// dispatch serves the methods of the tests:
+// Echo returns the body, Sleep waits for the milliseconds in the body, Meta returns the incoming meta.
+func dispatch(ctx context.Context, impl interface{}, req *wrpc_go.Request, enc wrpc_go.Encoder) ([]byte, error) {
+    switch req.Method {
+    case "Echo":
+        return req.Body, nil
+    case "Sleep":
+        ms, _ := strconv.Atoi(string(req.Body))
+        select {
+        case <- time.After(time.Duration(ms) * time.Millisecond):
+            return req.Body, nil
+        case <- ctx.Done():
+            return nil, ctx.Err()
+        }
+    case "Meta":
+        meta, _ := wrpc_go.FromIncomingContext(ctx)
+        return json.Marshal(meta)
+    }
+    return nil, wrpc_go.ErrMethodNotFound
+}
+
+func startServer(t *testing.T, opts ...wrpc_go.ServerOption) *wrpctest.Server {
+    ts := wrpctest.NewServer()
+    t.Cleanup(func() {
+        ts.Close()
+    })
+    if err := ts.Start(wrpc_go.NewRPCServer(testServer, nil, dispatch, ts.ServerOptions(opts...)...)); err != nil {
+        t.Fatal(err)
+    }
+    return ts
+}
+
+func newClient(t *testing.T, ts *wrpctest.Server, opts ...wrpc_go.ClientOption) *wrpc_go.Client {
+    client := ts.NewClient(testServer, opts...)
+    t.Cleanup(func() {
+        client.Close()
+    })
+    return client
+}
+
+func invoke(client *wrpc_go.Client, ctx context.Context, method, body string, meta ...map[string]string) (string, error) {
+    out, err := client.Invoke(ctx, "json", "", method, []byte(body), meta...)
+    return string(out), err
+}
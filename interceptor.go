@@ -0,0 +1,126 @@
+package wrpc_go
+
+import "context"
+
+type ServerInfo struct {
+    Server string
+    Method string
+    Impl   interface{}
+}
+
+type ServerHandler func(ctx context.Context, req *Request) ([]byte, error)
+
+// ServerInterceptor wraps a call on the server side. It may inspect or change the request,
+// call handler to continue the chain, or return early with an error, e.g. uerror.NewError,
+// whose code and message are sent back in Response.Code/CodeStatus.
+type ServerInterceptor func(ctx context.Context, req *Request, info *ServerInfo, handler ServerHandler) ([]byte, error)
+
+func chainServerInterceptors(interceptors []ServerInterceptor) ServerInterceptor {
+    if len(interceptors) == 0 {
+        return nil
+    }
+    if len(interceptors) == 1 {
+        return interceptors[0]
+    }
+    return func(ctx context.Context, req *Request, info *ServerInfo, handler ServerHandler) ([]byte, error) {
+        return interceptors[0](ctx, req, info, chainServerHandler(interceptors, 0, info, handler))
+    }
+}
+
+func chainServerHandler(interceptors []ServerInterceptor, curr int, info *ServerInfo, final ServerHandler) ServerHandler {
+    if curr == len(interceptors)-1 {
+        return final
+    }
+    return func(ctx context.Context, req *Request) ([]byte, error) {
+        return interceptors[curr+1](ctx, req, info, chainServerHandler(interceptors, curr+1, info, final))
+    }
+}
+
+// ClientCall describes a single Client.Invoke. Interceptors may change any field before
+// calling the invoker; Addr is set to the address the request was sent to once it returns.
+type ClientCall struct {
+    Method     string
+    Addr       string
+    EncodeType string
+    Meta       Meta
+    Body       []byte
+}
+
+type ClientInvoker func(ctx context.Context, call *ClientCall) ([]byte, error)
+
+type ClientInterceptor func(ctx context.Context, call *ClientCall, invoker ClientInvoker) ([]byte, error)
+
+func chainClientInterceptors(interceptors []ClientInterceptor) ClientInterceptor {
+    if len(interceptors) == 0 {
+        return nil
+    }
+    if len(interceptors) == 1 {
+        return interceptors[0]
+    }
+    return func(ctx context.Context, call *ClientCall, invoker ClientInvoker) ([]byte, error) {
+        return interceptors[0](ctx, call, chainClientInvoker(interceptors, 0, invoker))
+    }
+}
+
+func chainClientInvoker(interceptors []ClientInterceptor, curr int, final ClientInvoker) ClientInvoker {
+    if curr == len(interceptors)-1 {
+        return final
+    }
+    return func(ctx context.Context, call *ClientCall) ([]byte, error) {
+        return interceptors[curr+1](ctx, call, chainClientInvoker(interceptors, curr+1, final))
+    }
+}
+
+type StreamHandler func(stream ServerStream) error
+
+// ServerStreamInterceptor wraps a stream on the server side like ServerInterceptor does a call.
+// It may wrap stream, call handler to continue the chain, or return early with an error which
+// ends the stream.
+type ServerStreamInterceptor func(stream ServerStream, info *ServerInfo, handler StreamHandler) error
+
+func chainServerStreamInterceptors(interceptors []ServerStreamInterceptor) ServerStreamInterceptor {
+    if len(interceptors) == 0 {
+        return nil
+    }
+    if len(interceptors) == 1 {
+        return interceptors[0]
+    }
+    return func(stream ServerStream, info *ServerInfo, handler StreamHandler) error {
+        return interceptors[0](stream, info, chainStreamHandler(interceptors, 0, info, handler))
+    }
+}
+
+func chainStreamHandler(interceptors []ServerStreamInterceptor, curr int, info *ServerInfo, final StreamHandler) StreamHandler {
+    if curr == len(interceptors)-1 {
+        return final
+    }
+    return func(stream ServerStream) error {
+        return interceptors[curr+1](stream, info, chainStreamHandler(interceptors, curr+1, info, final))
+    }
+}
+
+// ClientStreamer opens the stream described by call, Body is not used.
+type ClientStreamer func(ctx context.Context, call *ClientCall) (ClientStream, error)
+
+type ClientStreamInterceptor func(ctx context.Context, call *ClientCall, streamer ClientStreamer) (ClientStream, error)
+
+func chainClientStreamInterceptors(interceptors []ClientStreamInterceptor) ClientStreamInterceptor {
+    if len(interceptors) == 0 {
+        return nil
+    }
+    if len(interceptors) == 1 {
+        return interceptors[0]
+    }
+    return func(ctx context.Context, call *ClientCall, streamer ClientStreamer) (ClientStream, error) {
+        return interceptors[0](ctx, call, chainClientStreamer(interceptors, 0, streamer))
+    }
+}
+
+func chainClientStreamer(interceptors []ClientStreamInterceptor, curr int, final ClientStreamer) ClientStreamer {
+    if curr == len(interceptors)-1 {
+        return final
+    }
+    return func(ctx context.Context, call *ClientCall) (ClientStream, error) {
+        return interceptors[curr+1](ctx, call, chainClientStreamer(interceptors, curr+1, final))
+    }
+}
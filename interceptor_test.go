@@ -0,0 +1,44 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "encoding/json"
+    "reflect"
+    "testing"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+)
+
+func TestClientInterceptorOrder(t *testing.T) {
+    ts := startServer(t)
+    var order []string
+    interceptor := func(name string) wrpc_go.ClientInterceptor {
+        return func(ctx context.Context, call *wrpc_go.ClientCall, invoker wrpc_go.ClientInvoker) ([]byte, error) {
+            order = append(order, name+" before")
+            // each one sees the meta set by the ones before it.
+            call.Meta.Set("chain", call.Meta.Get("chain")+name)
+            out, err := invoker(ctx, call)
+            order = append(order, name+" after")
+            return out, err
+        }
+    }
+    client := newClient(t, ts,
+        wrpc_go.WithClientOptionInterceptors(interceptor("a"), interceptor("b")),
+        wrpc_go.WithClientOptionInterceptors(interceptor("c")))
+
+    out, err := invoke(client, context.Background(), "Meta", `""`, map[string]string{"chain": ">"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []string{"a before", "b before", "c before", "c after", "b after", "a after"}
+    if !reflect.DeepEqual(order, want) {
+        t.Fatalf("interceptors ran in the order %v, want %v", order, want)
+    }
+    var meta map[string]string
+    if err := json.Unmarshal([]byte(out), &meta); err != nil {
+        t.Fatal(err)
+    }
+    if meta["chain"] != ">abc" {
+        t.Fatalf("server got chain %q, want >abc", meta["chain"])
+    }
+}
@@ -20,6 +20,9 @@ func (m Meta)Get(k string) string {
     return m[k]
 }
 
+type wrpcIncomingKey struct {}
+
+// FromOutgoingContext returns the meta sent with the calls made with ctx.
 func FromOutgoingContext(ctx context.Context) (Meta, bool) {
     value := ctx.Value(wrpcContextkey{})
     md, ok := value.(Meta)
@@ -30,7 +33,32 @@ func NewOutgoingContext(ctx context.Context, meta Meta) context.Context {
     return context.WithValue(ctx, wrpcContextkey{}, meta)
 }
 
+// FromIncomingContext returns the meta of the request a handler is serving. It is not sent
+// with the calls the handler makes, copy the keys to forward into an outgoing context.
+func FromIncomingContext(ctx context.Context) (Meta, bool) {
+    value := ctx.Value(wrpcIncomingKey{})
+    md, ok := value.(Meta)
+    return md, ok
+}
+
+func NewIncomingContext(ctx context.Context, meta Meta) context.Context {
+    return context.WithValue(ctx, wrpcIncomingKey{}, meta)
+}
+
 const (
     EncodeType = "encode-type"
     ConsistentHashKey = "consistenthash"
+    // CompressType set in the meta of a call overrides the compressor of the client,
+    // CompressNone turns compression off for the call.
+    CompressType = "compress-type"
+)
+
+const (
+    goAwayKey = "wrpc-goaway"
+    // reqCompressKey is the compressor of the request body, acceptCompressKey the one the client
+    // wants the response body compressed with and respCompressKey the one the server used.
+    // Responses echo the meta of the request, so the keys of request and response differ.
+    reqCompressKey = "wrpc-req-compress"
+    acceptCompressKey = "wrpc-accept-compress"
+    respCompressKey = "wrpc-resp-compress"
 )
@@ -0,0 +1,259 @@
+package wrpc_go
+
+import (
+    "math"
+    "sort"
+    "sync"
+    "time"
+)
+
+// OutlierConfig configures outlier detection. Every Interval the endpoints with at least MinRequests
+// requests are compared, when there are MinHosts of them. An endpoint is ejected when its success
+// rate is more than SuccessRateStdevFactor standard deviations below the mean, or when its latency
+// at LatencyPercentile is more than LatencyFactor times the median of the group. Each ejection of
+// an endpoint lasts twice as long as the one before, starting at BaseEjectionTime and capped at
+// MaxEjectionTime, and no more than MaxEjectionPercent of the endpoints are ejected at once,
+// one at least.
+// Outlier detection is off unless Enable is set.
+type OutlierConfig struct {
+    Enable                 bool          `yaml:"enable"`
+    Interval               time.Duration `yaml:"interval"`
+    BaseEjectionTime       time.Duration `yaml:"base-ejection-time"`
+    MaxEjectionTime        time.Duration `yaml:"max-ejection-time"`
+    MaxEjectionPercent     int           `yaml:"max-ejection-percent"`
+    MinRequests            int           `yaml:"min-requests"`
+    MinHosts               int           `yaml:"min-hosts"`
+    SuccessRateStdevFactor float64       `yaml:"success-rate-stdev-factor"`
+    LatencyPercentile      float64       `yaml:"latency-percentile"`
+    LatencyFactor          float64       `yaml:"latency-factor"`
+}
+
+func defaultOutlierConfig() *OutlierConfig {
+    return &OutlierConfig{
+        Enable:                 false,
+        Interval:               10000,
+        BaseEjectionTime:       30000,
+        MaxEjectionTime:        300000,
+        MaxEjectionPercent:     10,
+        MinRequests:            100,
+        MinHosts:               5,
+        SuccessRateStdevFactor: 1.9,
+        LatencyPercentile:      0.99,
+        LatencyFactor:          3,
+    }
+}
+
+const outlierLatencySamples = 512
+
+// outlierStats collects the results of one interval and the ejection state of an endpoint.
+type outlierStats struct {
+    mu           sync.Mutex
+    requests     int
+    failures     int
+    latencies    []time.Duration
+    next         int
+    ejections    int
+    ejectedUntil time.Time
+    successRate  float64
+    latency      time.Duration
+}
+
+func newOutlierStats() *outlierStats {
+    return &outlierStats{
+        latencies: make([]time.Duration, 0, outlierLatencySamples),
+        successRate: 1,
+    }
+}
+
+func (s *outlierStats)record(result int, latency time.Duration) {
+    if result == breakerIgnore {
+        return
+    }
+    s.mu.Lock()
+    s.requests++
+    if result == breakerFailure {
+        s.failures++
+    }
+    // only the last outlierLatencySamples latencies of the interval are kept.
+    if len(s.latencies) < outlierLatencySamples {
+        s.latencies = append(s.latencies, latency)
+    } else {
+        s.latencies[s.next] = latency
+        s.next = (s.next + 1) % outlierLatencySamples
+    }
+    s.mu.Unlock()
+}
+
+func (s *outlierStats)ejected() bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return time.Now().Before(s.ejectedUntil)
+}
+
+// outlierSample is what an endpoint did during the last interval.
+type outlierSample struct {
+    connect     *connector
+    requests    int
+    successRate float64
+    latency     time.Duration
+}
+
+// collect resets the interval and returns its numbers. Every interval without failure after an
+// ejection is over takes one ejection back, so the next one is shorter again.
+func (s *outlierStats)collect(percentile float64) outlierSample {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sample := outlierSample{
+        requests: s.requests,
+        successRate: 1,
+    }
+    if s.requests > 0 {
+        sample.successRate = float64(s.requests-s.failures) / float64(s.requests)
+    }
+    if len(s.latencies) > 0 {
+        latencies := append([]time.Duration(nil), s.latencies...)
+        sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+        idx := int(math.Ceil(percentile*float64(len(latencies)))) - 1
+        if idx < 0 {
+            idx = 0
+        }
+        if idx >= len(latencies) {
+            idx = len(latencies) - 1
+        }
+        sample.latency = latencies[idx]
+    }
+    if s.requests > 0 {
+        s.successRate, s.latency = sample.successRate, sample.latency
+    }
+    if s.ejections > 0 && !time.Now().Before(s.ejectedUntil) && s.failures == 0 {
+        s.ejections--
+    }
+    s.requests, s.failures = 0, 0
+    s.latencies = s.latencies[:0]
+    s.next = 0
+    return sample
+}
+
+func (s *outlierStats)eject(conf *OutlierConfig) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    ejection := conf.BaseEjectionTime
+    for i := 0; i < s.ejections && (conf.MaxEjectionTime <= 0 || ejection < conf.MaxEjectionTime); i++ {
+        ejection *= 2
+    }
+    if conf.MaxEjectionTime > 0 && ejection > conf.MaxEjectionTime {
+        ejection = conf.MaxEjectionTime
+    }
+    s.ejections++
+    s.ejectedUntil = time.Now().Add(ejection)
+}
+
+func (client *Client)detectOutliers() {
+    conf := client.opts.outlier
+    if conf == nil || !conf.Enable || conf.Interval <= 0 {
+        return
+    }
+    ticker := time.NewTicker(conf.Interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <- ticker.C:
+        case <- client.closed:
+            return
+        }
+        client.mu.Lock()
+        connectors := append([]*connector(nil), client.connectors...)
+        client.mu.Unlock()
+        ejectOutliers(conf, connectors)
+    }
+}
+
+func ejectOutliers(conf *OutlierConfig, connectors []*connector) {
+    if len(connectors) == 0 {
+        return
+    }
+    ejected := 0
+    samples := make([]outlierSample, 0, len(connectors))
+    for _, connect := range connectors {
+        sample := connect.outlier.collect(conf.LatencyPercentile)
+        if connect.outlier.ejected() {
+            ejected++
+            continue
+        }
+        if sample.requests >= conf.MinRequests {
+            sample.connect = connect
+            samples = append(samples, sample)
+        }
+    }
+    if len(samples) == 0 || len(samples) < conf.MinHosts {
+        return
+    }
+
+    var mean, variance float64
+    for _, sample := range samples {
+        mean += sample.successRate
+    }
+    mean /= float64(len(samples))
+    for _, sample := range samples {
+        variance += (sample.successRate - mean) * (sample.successRate - mean)
+    }
+    successThreshold := mean - conf.SuccessRateStdevFactor*math.Sqrt(variance/float64(len(samples)))
+
+    latencies := make([]time.Duration, 0, len(samples))
+    for _, sample := range samples {
+        latencies = append(latencies, sample.latency)
+    }
+    sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+    latencyThreshold := time.Duration(float64(latencies[len(latencies)/2]) * conf.LatencyFactor)
+
+    // like envoy, one endpoint can always be ejected, or small groups would never eject any.
+    maxEjected := conf.MaxEjectionPercent * len(connectors) / 100
+    if maxEjected < 1 {
+        maxEjected = 1
+    }
+    for _, sample := range samples {
+        slow := conf.LatencyFactor > 0 && latencyThreshold > 0 && sample.latency > latencyThreshold
+        if sample.successRate < successThreshold || slow {
+            if ejected >= maxEjected {
+                return
+            }
+            sample.connect.outlier.eject(conf)
+            ejected++
+        }
+    }
+}
+
+// EndpointStatus is the state of an endpoint as seen by the client.
+type EndpointStatus struct {
+    Addr         string
+    Weight       int
+    Outstanding  int64
+    Draining     bool
+    Breaker      BreakerState
+    Ejected      bool
+    EjectedUntil time.Time
+    // SuccessRate and Latency are the numbers of the last outlier detection interval with requests.
+    SuccessRate  float64
+    Latency      time.Duration
+}
+
+func (c *connector)status() EndpointStatus {
+    c.outlier.mu.Lock()
+    ejectedUntil := c.outlier.ejectedUntil
+    successRate, latency := c.outlier.successRate, c.outlier.latency
+    c.outlier.mu.Unlock()
+    status := EndpointStatus{
+        Addr: c.addr,
+        Weight: c.Weight(),
+        Outstanding: c.Outstanding(),
+        Draining: c.isDraining(),
+        Breaker: c.breaker.State(),
+        SuccessRate: successRate,
+        Latency: latency,
+    }
+    if time.Now().Before(ejectedUntil) {
+        status.Ejected = true
+        status.EjectedUntil = ejectedUntil
+    }
+    return status
+}
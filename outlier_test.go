@@ -0,0 +1,150 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+    "github.com/wukong-cloud/wrpc-go/wrpctest"
+)
+
+// outlierClient starts hosts servers, the last failing ones fail every call, and returns a
+// client of them.
+func outlierClient(t *testing.T, conf *wrpc_go.OutlierConfig, hosts, failing int) (*wrpc_go.Client, []*wrpctest.Server) {
+    servers := make([]*wrpctest.Server, hosts)
+    addrs := make([]string, hosts)
+    for i := range servers {
+        servers[i] = startServer(t)
+        addrs[i] = servers[i].Addr
+        // the same latency everywhere, no endpoint is ejected for being slow.
+        servers[i].InjectLatency("Echo", time.Millisecond)
+        if i >= hosts-failing {
+            servers[i].InjectError("Echo", uerror.NewError(503, "down"), 0)
+        }
+    }
+    opts := []wrpc_go.ClientOption{
+        wrpc_go.WithClientOptionAddr(strings.Join(addrs, ";")),
+        wrpc_go.WithClientOptionDialer(dialer(servers...)),
+    }
+    if conf != nil {
+        opts = append(opts, wrpc_go.WithClientOptionOutlierDetection(conf))
+    }
+    return newClient(t, servers[0], opts...), servers
+}
+
+// sendEach calls every endpoint with workers goroutines each until done is closed.
+func sendEach(client *wrpc_go.Client, workers int, done chan struct{}) *sync.WaitGroup {
+    var wg sync.WaitGroup
+    for _, addr := range client.GetAllEndpoints() {
+        for i := 0; i < workers; i++ {
+            wg.Add(1)
+            go func(addr string) {
+                defer wg.Done()
+                for {
+                    select {
+                    case <- done:
+                        return
+                    default:
+                    }
+                    client.Invoke(context.Background(), "json", addr, "Echo", []byte(`"a"`))
+                }
+            }(addr)
+        }
+    }
+    return &wg
+}
+
+// detect sends calls during two detection intervals.
+func detect(client *wrpc_go.Client, workers int, interval time.Duration) {
+    done := make(chan struct{})
+    wg := sendEach(client, workers, done)
+    time.Sleep(interval*2 + interval/2)
+    close(done)
+    wg.Wait()
+}
+
+func ejected(client *wrpc_go.Client) []bool {
+    var ejected []bool
+    for _, status := range client.GetEndpointStatus() {
+        ejected = append(ejected, status.Ejected)
+    }
+    return ejected
+}
+
+func outlierConfig(maxEjectionPercent int) *wrpc_go.OutlierConfig {
+    return &wrpc_go.OutlierConfig{
+        Enable: true,
+        Interval: 100 * time.Millisecond,
+        BaseEjectionTime: time.Minute,
+        MaxEjectionPercent: maxEjectionPercent,
+        MinRequests: 5,
+        MinHosts: 2,
+        SuccessRateStdevFactor: 0.5,
+    }
+}
+
+func TestOutlierEjection(t *testing.T) {
+    client, _ := outlierClient(t, outlierConfig(50), 2, 1)
+    detect(client, 1, 100 * time.Millisecond)
+    if got := ejected(client); len(got) != 2 || got[0] || !got[1] {
+        t.Fatalf("ejected %v, want only the failing endpoint", got)
+    }
+}
+
+func TestOutlierEjectionCap(t *testing.T) {
+    // 25% of 4 endpoints is one, the second failing endpoint stays.
+    client, _ := outlierClient(t, outlierConfig(25), 4, 2)
+    detect(client, 1, 100 * time.Millisecond)
+    count := 0
+    for _, e := range ejected(client) {
+        if e {
+            count++
+        }
+    }
+    if count != 1 {
+        t.Fatalf("ejected %v, want one endpoint", ejected(client))
+    }
+}
+
+func TestOutlierDefaultConfig(t *testing.T) {
+    startServer(t)
+    conf := *wrpc_go.GetClientConfig().OutlierDetection
+    conf.Enable = true
+    conf.Interval = 400 * time.Millisecond
+    // 10% of 5 endpoints is below one, one is ejected all the same.
+    client, servers := outlierClient(t, &conf, 5, 1)
+    // MinRequests calls to every endpoint within an interval.
+    detect(client, 8, conf.Interval)
+    failing := servers[4].Addr
+
+    status := client.GetEndpointStatus()
+    for _, s := range status {
+        if s.Ejected != (s.Addr == failing) {
+            t.Fatalf("%s ejected %v", s.Addr, s.Ejected)
+        }
+        if s.Addr == failing && (s.SuccessRate != 0 || !s.EjectedUntil.After(time.Now())) {
+            t.Fatalf("status of the ejected endpoint %+v", s)
+        }
+    }
+    // the ejected endpoint is still listed.
+    addrs := client.GetAllEndpoints()
+    if len(addrs) != 5 || len(status) != 5 {
+        t.Fatalf("listed %v", addrs)
+    }
+    for i := range addrs {
+        if addrs[i] != status[i].Addr {
+            t.Fatalf("GetAllEndpoints %v and GetEndpointStatus differ", addrs)
+        }
+    }
+}
+
+func TestOutlierOffByDefault(t *testing.T) {
+    startServer(t)
+    if conf := wrpc_go.GetClientConfig().OutlierDetection; conf == nil || conf.Enable {
+        t.Fatalf("outlier detection is on by default: %+v", conf)
+    }
+}
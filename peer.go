@@ -0,0 +1,29 @@
+package wrpc_go
+
+import (
+    "context"
+    "crypto/tls"
+    "net"
+)
+
+// Peer is the other end of the connection a request came in on.
+type Peer struct {
+    Addr      net.Addr
+    LocalAddr net.Addr
+    // TLSState is nil on plaintext connections.
+    TLSState  *tls.ConnectionState
+    // ConnID tells the connections of a server apart, requests of one connection share it.
+    ConnID    uint64
+}
+
+type peerKey struct{}
+
+func newPeerContext(ctx context.Context, p *Peer) context.Context {
+    return context.WithValue(ctx, peerKey{}, p)
+}
+
+// PeerFromContext returns the peer of the request a handler is serving.
+func PeerFromContext(ctx context.Context) (*Peer, bool) {
+    p, ok := ctx.Value(peerKey{}).(*Peer)
+    return p, ok
+}
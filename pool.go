@@ -0,0 +1,101 @@
+package wrpc_go
+
+import (
+    "time"
+)
+
+const (
+    poolMaintainInterval = 10 * time.Second
+    minPoolMaintainInterval = 10 * time.Millisecond
+)
+
+// maintainPool closes the connections idle for longer than maxIdleTime and dials the endpoints
+// that fell below minConn again, e.g. after the server closed some connections.
+func (client *Client)maintainPool() {
+    maxIdleTime, minConn := client.opts.maxIdleTime, client.opts.minConn
+    if maxIdleTime <= 0 && minConn <= 0 {
+        return
+    }
+    interval := poolMaintainInterval
+    if maxIdleTime > 0 && maxIdleTime/2 < interval {
+        interval = maxIdleTime / 2
+    }
+    if interval < minPoolMaintainInterval {
+        interval = minPoolMaintainInterval
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case now := <- ticker.C:
+            client.mu.Lock()
+            connectors := client.connectors
+            client.mu.Unlock()
+            for _, connect := range connectors {
+                if maxIdleTime > 0 {
+                    connect.reapIdle(now, maxIdleTime, minConn)
+                }
+                connect.warm()
+            }
+        case <- client.closed:
+            return
+        }
+    }
+}
+
+// reapIdle closes the connections without a call for maxIdleTime, keeping at least minConn.
+func (c *connector)reapIdle(now time.Time, maxIdleTime time.Duration, minConn int) {
+    c.mu.Lock()
+    keep := make([]*clientConn, 0, len(c.conns))
+    idle := make([]*clientConn, 0)
+    for _, conn := range c.conns {
+        if len(c.conns)-len(idle) > minConn && conn.idle(now, maxIdleTime) {
+            idle = append(idle, conn)
+            continue
+        }
+        keep = append(keep, conn)
+    }
+    c.conns = keep
+    c.mu.Unlock()
+    for _, conn := range idle {
+        conn.close()
+    }
+}
+
+// warm dials until the endpoint has minConn connections, so the first calls do not wait for it.
+// Dial errors are left to getConn, which reports them to the caller. A draining endpoint is
+// going away, it is warmed again once its draining connections are closed.
+func (c *connector)warm() {
+    minConn := c.client.opts.minConn
+    for {
+        c.mu.Lock()
+        need := !c.closed && !c.isDraining() && len(c.conns) < minConn
+        c.mu.Unlock()
+        if !need {
+            return
+        }
+        rw, err := c.client.dial(c.addr)
+        if err != nil {
+            return
+        }
+        conn := newClientConn(c, rw)
+        c.mu.Lock()
+        if c.closed || len(c.conns) >= c.client.opts.maxConn {
+            c.mu.Unlock()
+            conn.close()
+            return
+        }
+        c.conns = append(c.conns, conn)
+        c.mu.Unlock()
+    }
+}
+
+// idle reports whether the connection has no call in flight and started none for maxIdleTime.
+func (conn *clientConn)idle(now time.Time, maxIdleTime time.Duration) bool {
+    conn.mu.Lock()
+    defer conn.mu.Unlock()
+    if len(conn.pending) > 0 || len(conn.streams) > 0 {
+        return false
+    }
+    return now.Sub(conn.useAt) >= maxIdleTime
+}
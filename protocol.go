@@ -1,10 +1,13 @@
 package wrpc_go
 
 import (
+    "bytes"
     "encoding/binary"
     "github.com/golang/protobuf/proto"
+    "hash/crc32"
 )
 
+// Protocol encodes the messages carried in a frame, the frame header is added by the connection.
 type Protocol interface {
     PacketRequest(request *Request) ([]byte, error)
     UnPacketRequest(body []byte) (*Request, error)
@@ -22,19 +25,12 @@ func newWRPCProtocol() Protocol {
 }
 
 func (jp *wrpcProtocol) PacketRequest(request *Request) ([]byte, error) {
-    bs, err := proto.Marshal(request)
-    if err != nil {
-        return nil, err
-    }
-    pkg := make([]byte, len(bs)+4)
-    binary.LittleEndian.PutUint32(pkg, uint32(len(bs)+4))
-    copy(pkg[4:], bs)
-    return pkg, nil
+    return proto.Marshal(request)
 }
 
 func (jp *wrpcProtocol) UnPacketRequest(body []byte) (*Request, error) {
     req := Request{}
-    err := proto.Unmarshal(body[4:], &req)
+    err := proto.Unmarshal(body, &req)
     if err != nil {
         return nil, err
     }
@@ -42,19 +38,12 @@ func (jp *wrpcProtocol) UnPacketRequest(body []byte) (*Request, error) {
 }
 
 func (jp *wrpcProtocol) PacketResponse(response *Response) ([]byte, error) {
-    bs, err := proto.Marshal(response)
-    if err != nil {
-        return nil, err
-    }
-    pkg := make([]byte, len(bs)+4)
-    binary.LittleEndian.PutUint32(pkg, uint32(len(bs)+4))
-    copy(pkg[4:], bs)
-    return pkg, nil
+    return proto.Marshal(response)
 }
 
 func (jp *wrpcProtocol) UnPacketResponse(body []byte) (*Response, error) {
     resp := Response{}
-    err := proto.Unmarshal(body[4:], &resp)
+    err := proto.Unmarshal(body, &resp)
     if err != nil {
         return nil, err
     }
@@ -64,3 +53,111 @@ func (jp *wrpcProtocol) UnPacketResponse(body []byte) (*Response, error) {
 func (jp *wrpcProtocol) Name() string {
     return "proto-protocol"
 }
+
+// A v1 frame is a 4-byte little-endian total length followed by the payload.
+// A v2 frame starts with a 12-byte header:
+//
+//    magic "WRPC" | version | message type | flags | reserved | total length (LE uint32)
+//
+// followed by a CRC32 (IEEE) of the payload when flagChecksum is set, then the payload.
+// Read as a v1 length the magic is over 1GB, so both versions can be told apart per frame.
+const (
+    ProtocolV1 = 1
+    ProtocolV2 = 2
+)
+
+const (
+    msgTypeRequest  = 1
+    msgTypeResponse = 2
+    msgTypePing     = 3
+    msgTypePong     = 4
+    msgTypeCancel   = 5
+    msgTypeGoAway   = 6
+)
+
+const (
+    flagChecksum = 1 << 0
+)
+
+const (
+    v1HeaderLen = 4
+    v2HeaderLen = 12
+    checksumLen = 4
+)
+
+var frameMagic = []byte("WRPC")
+
+type frame struct {
+    version uint8
+    msgType uint8
+    flags   uint8
+    payload []byte
+}
+
+func encodeFrame(version, msgType, flags uint8, payload []byte) []byte {
+    if version == ProtocolV1 {
+        pkg := make([]byte, v1HeaderLen+len(payload))
+        binary.LittleEndian.PutUint32(pkg, uint32(len(pkg)))
+        copy(pkg[v1HeaderLen:], payload)
+        return pkg
+    }
+    headerLen := v2HeaderLen
+    if flags&flagChecksum != 0 {
+        headerLen += checksumLen
+    }
+    pkg := make([]byte, headerLen+len(payload))
+    copy(pkg, frameMagic)
+    pkg[4] = version
+    pkg[5] = msgType
+    pkg[6] = flags
+    binary.LittleEndian.PutUint32(pkg[8:], uint32(len(pkg)))
+    if flags&flagChecksum != 0 {
+        binary.LittleEndian.PutUint32(pkg[v2HeaderLen:], crc32.ChecksumIEEE(payload))
+    }
+    copy(pkg[headerLen:], payload)
+    return pkg
+}
+
+func readFrame(bs []byte) (*frame, int, int) {
+    if len(bs) <= v1HeaderLen {
+        return nil, 0, state_need_read
+    }
+    if !bytes.Equal(bs[:len(frameMagic)], frameMagic) {
+        n := int(binary.LittleEndian.Uint32(bs))
+        if n <= v1HeaderLen {
+            return nil, 0, state_err
+        }
+        if n > len(bs) {
+            return nil, 0, state_need_read
+        }
+        return &frame{version: ProtocolV1, payload: bs[v1HeaderLen:n]}, n, state_full
+    }
+
+    if len(bs) < v2HeaderLen {
+        return nil, 0, state_need_read
+    }
+    f := &frame{
+        version: bs[4],
+        msgType: bs[5],
+        flags: bs[6],
+    }
+    if f.version != ProtocolV2 {
+        return nil, 0, state_err
+    }
+    headerLen := v2HeaderLen
+    if f.flags&flagChecksum != 0 {
+        headerLen += checksumLen
+    }
+    n := int(binary.LittleEndian.Uint32(bs[8:]))
+    if n < headerLen {
+        return nil, 0, state_err
+    }
+    if n > len(bs) {
+        return nil, 0, state_need_read
+    }
+    f.payload = bs[headerLen:n]
+    if f.flags&flagChecksum != 0 && binary.LittleEndian.Uint32(bs[v2HeaderLen:]) != crc32.ChecksumIEEE(f.payload) {
+        return nil, 0, state_err
+    }
+    return f, n, state_full
+}
@@ -0,0 +1,155 @@
+package wrpc_go
+
+import (
+    "context"
+    "math"
+    "math/rand"
+    "sync"
+    "time"
+
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+)
+
+// RetryPolicy decides when a failed Invoke is sent again, each attempt goes to an endpoint that
+// was not tried yet when there is one. A request that could not be written is always retried.
+// Once the server may have seen it, it is only retried when the response code is one of
+// RetryableCodes and the method is listed in IdempotentMethods, "*" matching every method.
+// Retries are limited by a budget: every request earns BudgetRatio retry, and BudgetMinRetries
+// retries per second are allowed whatever the traffic.
+// An attempt that is still waiting after PerAttemptTimeout fails with ErrRequestTimeout, whose
+// code 405 must be in RetryableCodes for it to be retried. Without PerAttemptTimeout a timeout
+// is the one of the whole call and is never retried. Like the breaker, fields missing from the
+// config file keep their defaults while a policy given as option is used as it is. Without
+// BudgetRatio and BudgetMinRetries retries are not limited by a budget.
+type RetryPolicy struct {
+    MaxAttempts       int           `yaml:"max-attempts"`
+    PerAttemptTimeout time.Duration `yaml:"per-attempt-timeout"`
+    InitialBackoff    time.Duration `yaml:"initial-backoff"`
+    MaxBackoff        time.Duration `yaml:"max-backoff"`
+    BackoffMultiplier float64       `yaml:"backoff-multiplier"`
+    Jitter            float64       `yaml:"jitter"`
+    RetryableCodes    []int32       `yaml:"retryable-codes"`
+    IdempotentMethods []string      `yaml:"idempotent-methods"`
+    BudgetRatio       float64       `yaml:"budget-ratio"`
+    BudgetMinRetries  int           `yaml:"budget-min-retries"`
+}
+
+func defaultRetryPolicy() *RetryPolicy {
+    return &RetryPolicy{
+        InitialBackoff:    10,
+        MaxBackoff:        1000,
+        BackoffMultiplier: 2,
+        Jitter:            0.2,
+        BudgetRatio:       0.2,
+        BudgetMinRetries:  10,
+    }
+}
+
+func (p *RetryPolicy)retryable(method string, err error) bool {
+    if !p.idempotent(method) {
+        return false
+    }
+    e, ok := err.(*uerror.Error)
+    if !ok {
+        return false
+    }
+    for _, code := range p.RetryableCodes {
+        if code == e.Code {
+            return true
+        }
+    }
+    return false
+}
+
+func (p *RetryPolicy)idempotent(method string) bool {
+    for _, m := range p.IdempotentMethods {
+        if m == method || m == "*" {
+            return true
+        }
+    }
+    return false
+}
+
+// backoff is the wait before the given retry, starting at 1.
+func (p *RetryPolicy)backoff(retry int) time.Duration {
+    multiplier := p.BackoffMultiplier
+    if multiplier < 1 {
+        multiplier = 1
+    }
+    backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+    if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+        backoff = float64(p.MaxBackoff)
+    }
+    if p.Jitter > 0 {
+        backoff *= 1 + p.Jitter*(2*rand.Float64()-1)
+    }
+    return time.Duration(backoff)
+}
+
+// retryBudgetRequests is how many requests the budget remembers, retries saved by quiet
+// periods do not pile up beyond it.
+const retryBudgetRequests = 1000
+
+type retryBudget struct {
+    ratio      float64
+    minRetries int
+
+    mu      sync.Mutex
+    tokens  float64
+    second  int64
+    retries int
+}
+
+func newRetryBudget(policy *RetryPolicy) *retryBudget {
+    if policy.BudgetRatio <= 0 && policy.BudgetMinRetries <= 0 {
+        return nil
+    }
+    return &retryBudget{
+        ratio: policy.BudgetRatio,
+        minRetries: policy.BudgetMinRetries,
+    }
+}
+
+func (b *retryBudget)deposit() {
+    if b == nil {
+        return
+    }
+    b.mu.Lock()
+    b.tokens = math.Min(b.tokens+b.ratio, b.ratio*retryBudgetRequests)
+    b.mu.Unlock()
+}
+
+func (b *retryBudget)withdraw() bool {
+    if b == nil {
+        return true
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if second := time.Now().Unix(); second != b.second {
+        b.second = second
+        b.retries = 0
+    }
+    if b.retries < b.minRetries {
+        b.retries++
+        return true
+    }
+    if b.tokens >= 1 {
+        b.tokens--
+        return true
+    }
+    return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+    if d <= 0 {
+        return ctx.Err() == nil
+    }
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <- timer.C:
+        return true
+    case <- ctx.Done():
+        return false
+    }
+}
@@ -0,0 +1,25 @@
+package wrpc_go
+
+import (
+    "testing"
+    "time"
+)
+
+func TestRetryPolicyConfig(t *testing.T) {
+    cfg, err := parseConfig([]byte("client-config:\n  retry-policy:\n    max-attempts: 3\n    jitter: 0\n"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    policy := cfg.ClientConfig.RetryPolicy
+    if policy.MaxAttempts != 3 || policy.Jitter != 0 {
+        t.Fatalf("configured fields not kept: %+v", policy)
+    }
+    if policy.InitialBackoff != 10 * time.Millisecond || policy.MaxBackoff != time.Second ||
+        policy.BackoffMultiplier != 2 || policy.BudgetRatio != 0.2 || policy.BudgetMinRetries != 10 {
+        t.Fatalf("missing fields do not have the defaults: %+v", policy)
+    }
+    // without jitter the backoff is exact.
+    if backoff := policy.backoff(3); backoff != 40 * time.Millisecond {
+        t.Fatalf("backoff %v, want 40ms", backoff)
+    }
+}
@@ -0,0 +1,70 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+)
+
+func TestRetryableCodes(t *testing.T) {
+    ts := startServer(t)
+    client := newClient(t, ts, wrpc_go.WithClientOptionRetryPolicy(&wrpc_go.RetryPolicy{
+        MaxAttempts: 3,
+        RetryableCodes: []int32{503},
+        IdempotentMethods: []string{"Echo"},
+    }))
+
+    ts.InjectError("Echo", uerror.NewError(503, "down"), 2)
+    if out, err := invoke(client, context.Background(), "Echo", "a"); err != nil || out != "a" {
+        t.Fatalf("got %q %v, want the third attempt to succeed", out, err)
+    }
+    if calls := ts.Calls("Echo"); calls != 3 {
+        t.Fatalf("server got %d calls, want 3", calls)
+    }
+}
+
+func TestRetryBudget(t *testing.T) {
+    ts := startServer(t)
+    client := newClient(t, ts, wrpc_go.WithClientOptionRetryPolicy(&wrpc_go.RetryPolicy{
+        MaxAttempts: 3,
+        RetryableCodes: []int32{503},
+        IdempotentMethods: []string{"*"},
+        BudgetRatio: 0.01,
+        BudgetMinRetries: 1,
+    }))
+
+    ts.InjectError("Echo", uerror.NewError(503, "down"), 0)
+    for i := 0; i < 5; i++ {
+        if _, err := invoke(client, context.Background(), "Echo", "a"); err == nil {
+            t.Fatal("expected the injected error")
+        }
+    }
+    // one retry per second is allowed, the calls may span two seconds.
+    if calls := ts.Calls("Echo"); calls < 6 || calls > 7 {
+        t.Fatalf("server got %d calls, want the budget to allow 1 or 2 retries", calls)
+    }
+}
+
+func TestRetryPerAttemptTimeout(t *testing.T) {
+    ts := startServer(t)
+    client := newClient(t, ts, wrpc_go.WithClientOptionRetryPolicy(&wrpc_go.RetryPolicy{
+        MaxAttempts: 2,
+        PerAttemptTimeout: 100 * time.Millisecond,
+        RetryableCodes: []int32{405},
+        IdempotentMethods: []string{"Echo"},
+    }))
+
+    // only the first attempt is slow.
+    ts.InjectLatency("Echo", time.Second)
+    time.AfterFunc(50 * time.Millisecond, ts.Clear)
+    start := time.Now()
+    if out, err := invoke(client, context.Background(), "Echo", "a"); err != nil || out != "a" {
+        t.Fatalf("got %q %v, want the second attempt to succeed", out, err)
+    }
+    if elapsed := time.Since(start); elapsed > 500 * time.Millisecond {
+        t.Fatalf("call took %v, the first attempt was not cut short", elapsed)
+    }
+}
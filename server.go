@@ -2,7 +2,10 @@ package wrpc_go
 
 import (
     "context"
+    "crypto/tls"
     "github.com/wukong-cloud/wrpc-go/internal/register"
+    "net"
+    "sync"
     "time"
 )
 
@@ -20,6 +23,40 @@ type Server interface {
     Target() *register.Target
 }
 
+// readyWaiter is implemented by the servers that tell when they are ready, App waits for them
+// before registering. The others are considered ready once Start is called.
+type readyWaiter interface {
+    // WaitReady blocks until Start has bound its listener, it returns the error of Start
+    // if binding failed, or ctx.Err() if neither happens in time.
+    WaitReady(ctx context.Context) error
+}
+
+type readyState struct {
+    once sync.Once
+    ch   chan struct{}
+    err  error
+}
+
+func newReadyState() *readyState {
+    return &readyState{ch: make(chan struct{})}
+}
+
+func (r *readyState)done(err error) {
+    r.once.Do(func() {
+        r.err = err
+        close(r.ch)
+    })
+}
+
+func (r *readyState)wait(ctx context.Context) error {
+    select {
+    case <- ctx.Done():
+        return ctx.Err()
+    case <- r.ch:
+        return r.err
+    }
+}
+
 type ServerOptions struct {
     ip            string
     port          string
@@ -28,18 +65,44 @@ type ServerOptions struct {
     invokeTimeout time.Duration
     readSize      int32
     tick          chan struct{}
+    interceptors  []ServerInterceptor
+    streamInterceptors []ServerStreamInterceptor
+    streamDispatcher StreamDispatcher
+    heartbeatInterval time.Duration
+    heartbeatTimeout  time.Duration
+    idleTimeout   time.Duration
+    compressThreshold int
+    maxMessageSize int
+    tls           *TLSConfig
+    tlsConfig     *tls.Config
+    listener      net.Listener
 }
 
 func loadServerOptions(name string, opts ...ServerOption) *ServerOptions {
     cfg := GetServerConfig(name)
+    if cfg == nil {
+        // a server missing from the config listens where its options say, with the defaults.
+        cfg = &ServerConfig{Name: name}
+        initServerConfig(cfg)
+    }
     option := &ServerOptions{
         readSize: cfg.ReadBufferSize,
         maxInvoke: cfg.MaxInvoke,
+        invokeTimeout: cfg.InvokeTimeout,
+        heartbeatInterval: cfg.HeartbeatInterval,
+        heartbeatTimeout: cfg.HeartbeatTimeout,
+        idleTimeout: cfg.IdleTimeout,
+        compressThreshold: cfg.CompressThreshold,
+        maxMessageSize: cfg.MaxMessageSize,
+        tls: cfg.TLS,
         addr: ":"+cfg.Port,
         ip: cfg.IP,
         port: cfg.Port,
     }
 
+    if cfg.Addr != "" {
+        option.addr = cfg.Addr
+    }
     for _, opt := range opts {
         opt(option)
     }
@@ -56,8 +119,96 @@ func WithServerOptionReadSize(size int32) ServerOption {
     }
 }
 
+// WithServerOptionAddr sets the address to listen on, "unix:///path/to/socket" for a unix domain socket.
 func WithServerOptionAddr(addr string) ServerOption {
     return func(opt *ServerOptions) {
         opt.addr = addr
     }
 }
+
+// WithServerOptionMaxInvoke limits the requests handled at once, the others wait for their turn.
+func WithServerOptionMaxInvoke(max int32) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.maxInvoke = max
+    }
+}
+
+// WithServerOptionInvokeTimeout caps how long a handler may run, whatever deadline the caller sent.
+func WithServerOptionInvokeTimeout(timeout time.Duration) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.invokeTimeout = timeout
+    }
+}
+
+// WithServerOptionHeartbeat pings v2 clients that sent nothing for interval and closes the
+// connection when the pong does not arrive within timeout. An interval <= 0 turns it off.
+func WithServerOptionHeartbeat(interval, timeout time.Duration) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.heartbeatInterval = interval
+        opt.heartbeatTimeout = timeout
+    }
+}
+
+// WithServerOptionIdleTimeout closes connections without a request for timeout, 0 keeps them.
+func WithServerOptionIdleTimeout(timeout time.Duration) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.idleTimeout = timeout
+    }
+}
+
+// WithServerOptionCompressThreshold sets the size from which responses are compressed for the
+// clients that asked for it.
+func WithServerOptionCompressThreshold(threshold int) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.compressThreshold = threshold
+    }
+}
+
+// WithServerOptionMaxMessageSize bounds the size a compressed request body decompresses to, the
+// request fails with uerror.ErrMessageTooLarge beyond it. size <= 0 removes the bound.
+func WithServerOptionMaxMessageSize(size int) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.maxMessageSize = size
+    }
+}
+
+// WithServerOptionTLS serves TLS with the certificates of conf, which are reloaded when they change.
+func WithServerOptionTLS(conf *TLSConfig) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.tls = conf
+    }
+}
+
+// WithServerOptionTLSConfig serves TLS with config, it takes precedence over WithServerOptionTLS.
+func WithServerOptionTLSConfig(config *tls.Config) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.tlsConfig = config
+    }
+}
+
+// WithServerOptionListener serves on listener instead of listening on the address of the config.
+func WithServerOptionListener(listener net.Listener) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.listener = listener
+    }
+}
+
+func WithServerOptionInterceptors(interceptors ...ServerInterceptor) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.interceptors = append(opt.interceptors, interceptors...)
+    }
+}
+
+// WithServerOptionStreamInterceptors wraps the streams, they run in the given order once the
+// stream is open.
+func WithServerOptionStreamInterceptors(interceptors ...ServerStreamInterceptor) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.streamInterceptors = append(opt.streamInterceptors, interceptors...)
+    }
+}
+
+func WithServerOptionStreamDispatcher(dispatcher StreamDispatcher) ServerOption {
+    return func(opt *ServerOptions) {
+        opt.streamDispatcher = dispatcher
+    }
+}
@@ -5,7 +5,6 @@ import (
     "fmt"
     "github.com/wukong-cloud/wrpc-go/internal/register"
     "github.com/wukong-cloud/wrpc-go/util/logx"
-    "net"
     "net/http"
     "sync"
 )
@@ -22,6 +21,7 @@ type HttpServer struct {
     name string
 
     target *register.Target
+    ready *readyState
 }
 
 func NewHttpServer(name string, handler http.Handler, opts...ServerOption) *HttpServer {
@@ -30,6 +30,7 @@ func NewHttpServer(name string, handler http.Handler, opts...ServerOption) *Http
             Handler: withHttpHandlerRecover(handler),
         },
         name: name,
+        ready: newReadyState(),
     }
     srv.opts = loadServerOptions(name, opts...)
     srv.Server.Addr = srv.opts.addr
@@ -42,18 +43,27 @@ func NewHttpServer(name string, handler http.Handler, opts...ServerOption) *Http
 }
 
 func (srv *HttpServer)Start() error {
-    listen, err := net.Listen("tcp", srv.opts.addr)
+    listen, err := srv.opts.listen()
     if err != nil {
+        srv.ready.done(err)
         return err
     }
-    logx.Logf("start http server %s listen %s", srv.name, srv.opts.addr)
-    return srv.Serve(listen)
+    logx.Logf("start http server %s listen %s", srv.name, listen.Addr())
+    srv.ready.done(nil)
+    if err := srv.Serve(listen); err != nil && err != http.ErrServerClosed {
+        return err
+    }
+    return nil
 }
 
 func (srv *HttpServer)Stop(ctx context.Context) error {
     return srv.Shutdown(ctx)
 }
 
+func (srv *HttpServer)WaitReady(ctx context.Context) error {
+    return srv.ready.wait(ctx)
+}
+
 func (srv *HttpServer)Name() string {
     return srv.name
 }
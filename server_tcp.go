@@ -2,13 +2,15 @@ package wrpc_go
 
 import (
     "context"
-    "encoding/binary"
+    "crypto/tls"
     "fmt"
+    "io"
     "github.com/wukong-cloud/wrpc-go/internal/register"
     "github.com/wukong-cloud/wrpc-go/util/logx"
     "github.com/wukong-cloud/wrpc-go/util/uerror"
     "net"
     "sync"
+    "sync/atomic"
     "time"
 )
 
@@ -29,9 +31,12 @@ type TcpServer struct {
 
     impl interface{}
     dispatcher Dispatcher
+    interceptor ServerInterceptor
+    streamInterceptor ServerStreamInterceptor
 
     doneChan chan struct{}
     running bool
+    ready *readyState
 }
 
 func NewRPCServer(name string, impl interface{}, dispatcher Dispatcher, opts ...ServerOption) *TcpServer {
@@ -42,8 +47,11 @@ func NewRPCServer(name string, impl interface{}, dispatcher Dispatcher, opts ...
 
         impl: impl,
         dispatcher: dispatcher,
+        ready: newReadyState(),
     }
     srv.opts = loadServerOptions(name, opts...)
+    srv.interceptor = chainServerInterceptors(srv.opts.interceptors)
+    srv.streamInterceptor = chainServerStreamInterceptors(srv.opts.streamInterceptors)
     srv.target = &register.Target{
         Name: name,
         IP: srv.opts.ip,
@@ -53,21 +61,35 @@ func NewRPCServer(name string, impl interface{}, dispatcher Dispatcher, opts ...
 }
 
 func (srv *TcpServer)Start() error {
-    listen, err := net.Listen("tcp", srv.opts.addr)
+    tlsConfig := srv.opts.tlsConfig
+    if tlsConfig == nil && srv.opts.tls != nil {
+        var err error
+        if tlsConfig, err = newServerTLSConfig(srv.opts.tls); err != nil {
+            srv.ready.done(err)
+            return err
+        }
+    }
+    listen, err := srv.opts.listen()
     if err != nil {
+        srv.ready.done(err)
         return err
     }
+    if tlsConfig != nil {
+        listen = tls.NewListener(listen, tlsConfig)
+    }
     srv.mu.Lock()
     if srv.running {
         srv.mu.Unlock()
+        listen.Close()
         return ErrServerIsRunning
     }
 
-    logx.Logf("start rpc server %s listen %s", srv.name, srv.opts.addr)
+    logx.Logf("start rpc server %s listen %s", srv.name, listen.Addr())
 
     srv.listen = listen
     srv.running = true
     srv.mu.Unlock()
+    srv.ready.done(nil)
 
     var tempDelay time.Duration
 
@@ -100,6 +122,13 @@ func (srv *TcpServer)Start() error {
     }
 }
 
+const (
+    shutdownPollInterval = 20 * time.Millisecond
+    // drainIdleTimeout is how long a drained connection must read nothing after the goAway,
+    // so requests the client sent before it got the goAway are still answered.
+    drainIdleTimeout = 100 * time.Millisecond
+)
+
 func (srv *TcpServer)Stop(ctx context.Context) error {
     srv.mu.Lock()
     if srv.running == false {
@@ -109,14 +138,71 @@ func (srv *TcpServer)Stop(ctx context.Context) error {
     srv.listen.Close()
     srv.running = false
     srv.closeDoneChanLocked()
-    conns := srv.conns
+    conns := make([]*tcpConn, 0, len(srv.conns))
+    for conn := range srv.conns {
+        conns = append(conns, conn)
+    }
+    srv.mu.Unlock()
+
+    goAwayAt := time.Now()
+    for _, conn := range conns {
+        conn.goAway()
+    }
+
+    var err error
+    timer := time.NewTicker(shutdownPollInterval)
+    defer timer.Stop()
+    for err == nil {
+        open, inflight := srv.closeDrained(time.Now(), goAwayAt)
+        if open == 0 {
+            break
+        }
+        select {
+        case <- ctx.Done():
+            err = ctx.Err()
+            logx.Logf("server %s force stop with %d requests in flight:%v", srv.name, inflight, err)
+        case <- timer.C:
+        }
+    }
+
+    srv.mu.Lock()
+    conns = conns[:0]
+    for conn := range srv.conns {
+        conns = append(conns, conn)
+    }
     srv.conns = make(map[*tcpConn]struct{})
     srv.mu.Unlock()
 
-    for conn := range conns {
+    for _, conn := range conns {
         conn.close()
     }
-    return nil
+    return err
+}
+
+// closeDrained closes the connections that are drained, it returns how many are left open and
+// the requests in flight on them.
+func (srv *TcpServer)closeDrained(now, goAwayAt time.Time) (int, int) {
+    srv.mu.Lock()
+    conns := make([]*tcpConn, 0, len(srv.conns))
+    for conn := range srv.conns {
+        conns = append(conns, conn)
+    }
+    srv.mu.Unlock()
+
+    open, inflight := 0, 0
+    for _, conn := range conns {
+        if conn.drained(now, goAwayAt) {
+            conn.close()
+            continue
+        }
+        open++
+        inflight += int(atomic.LoadInt32(&conn.invokeNum))
+    }
+    return open, inflight
+}
+
+func (srv *TcpServer)WaitReady(ctx context.Context) error {
+    return srv.ready.wait(ctx)
 }
 
 func (srv *TcpServer)Name() string {
@@ -127,6 +213,45 @@ func (srv *TcpServer)Target() *register.Target {
     return srv.target
 }
 
+func (srv *TcpServer)handle(ctx context.Context, req *Request, enc Encoder) ([]byte, error) {
+    handler := func(ctx context.Context, req *Request) ([]byte, error) {
+        return srv.dispatcher(ctx, srv.impl, req, enc)
+    }
+    if srv.interceptor == nil {
+        return handler(ctx, req)
+    }
+    info := &ServerInfo{
+        Server: srv.name,
+        Method: req.Method,
+        Impl: srv.impl,
+    }
+    return srv.interceptor(ctx, req, info, handler)
+}
+
+func (srv *TcpServer)handleStream(method string, stream ServerStream) error {
+    handler := func(stream ServerStream) error {
+        return srv.opts.streamDispatcher(srv.impl, method, stream)
+    }
+    if srv.streamInterceptor == nil {
+        return handler(stream)
+    }
+    info := &ServerInfo{
+        Server: srv.name,
+        Method: method,
+        Impl: srv.impl,
+    }
+    return srv.streamInterceptor(stream, info, handler)
+}
+
+// invokeTimeout is the budget left to the caller, capped by the server's own invoke timeout.
+func (srv *TcpServer)invokeTimeout(req *Request) time.Duration {
+    timeout := time.Duration(req.Timeout) * time.Millisecond
+    if max := srv.opts.invokeTimeout; max > 0 && (timeout <= 0 || timeout > max) {
+        timeout = max
+    }
+    return timeout
+}
+
 func (srv *TcpServer)getDoneChan() <-chan struct{} {
     srv.mu.Lock()
     defer srv.mu.Unlock()
@@ -164,13 +289,25 @@ func (srv *TcpServer)removeConn(conn *tcpConn) {
     srv.mu.Unlock()
 }
 
+var nextConnId uint64
+
 type tcpConn struct {
     ip string
     port string
     rw net.Conn
+    writer *connWriter
     srv *TcpServer
     mu sync.Mutex
     invokeNum int32
+    streams map[int64]*serverStream
+    cancels map[int64]context.CancelFunc
+    version uint32
+    flags uint32
+    heartbeat *heartbeat
+    lastActive int64
+    done chan struct{}
+    closeOnce sync.Once
+    peer *Peer
 }
 
 func newConn(srv *TcpServer, rw net.Conn) *tcpConn {
@@ -180,6 +317,24 @@ func newConn(srv *TcpServer, rw net.Conn) *tcpConn {
         srv: srv,
         ip: ip,
         port: port,
+        streams: make(map[int64]*serverStream),
+        cancels: make(map[int64]context.CancelFunc),
+        version: ProtocolV1,
+        heartbeat: newHeartbeat(srv.opts.heartbeatInterval, srv.opts.heartbeatTimeout),
+        lastActive: time.Now().UnixNano(),
+        done: make(chan struct{}),
+        peer: &Peer{
+            Addr: rw.RemoteAddr(),
+            LocalAddr: rw.LocalAddr(),
+            ConnID: atomic.AddUint64(&nextConnId, 1),
+        },
+    }
+    conn.writer = newConnWriter(rw, 0, func(error) {
+        rw.Close()
+    })
+    srv.addConn(conn)
+    if conn.heartbeat != nil || srv.opts.idleTimeout > 0 {
+        go conn.keepalive()
     }
     return conn
 }
@@ -188,6 +343,11 @@ func (conn *tcpConn) handle() {
     defer logx.Recover()
     defer conn.close()
 
+    if err := conn.handshake(); err != nil {
+        logx.Log(logx.Kv("message", "tls handshake failed"), logx.Kv("server", conn.srv.Name()), logx.Kv("ip", conn.ip), logx.Kv("error", err))
+        return
+    }
+
     var (
         buf = make([]byte, 0, conn.srv.opts.readSize)
         readBuf = make([]byte, conn.srv.opts.readSize)
@@ -198,12 +358,13 @@ func (conn *tcpConn) handle() {
         if err != nil {
             return
         }
+        conn.heartbeat.received()
         buf = append(buf, readBuf[:n]...)
         for {
-            body, n, state := readBody(buf)
+            f, n, state := readFrame(buf)
             if state == state_full {
                 buf = buf[n:]
-                go conn.invoke(body)
+                conn.dispatch(f)
                 continue
             }
             if state == state_need_read {
@@ -214,37 +375,337 @@ func (conn *tcpConn) handle() {
     }
 }
 
+// goAway tells the client that no new request should be sent over this connection,
+// requests already sent are still answered until the server stops.
+func (conn *tcpConn)goAway() {
+    version, flags := conn.peerVersion()
+    if version >= ProtocolV2 {
+        conn.send(encodeFrame(version, msgTypeGoAway, flags, nil))
+        return
+    }
+    // v1 clients ignore a response nobody waits for, newer ones read the meta.
+    conn.sendResponse(&Response{
+        Meta: map[string]string{goAwayKey: "1"},
+        Code: 200,
+        CodeStatus: "ok",
+    })
+}
+
+// peerVersion returns the version and checksum flag of the last frame received,
+// replies are sent the same way so v1 clients keep working.
+func (conn *tcpConn)peerVersion() (uint8, uint8) {
+    return uint8(atomic.LoadUint32(&conn.version)), uint8(atomic.LoadUint32(&conn.flags)) & flagChecksum
+}
+
+func (conn *tcpConn)sendResponse(resp *Response) error {
+    bs, err := conn.srv.protocol.PacketResponse(resp)
+    if err != nil {
+        return err
+    }
+    version, flags := conn.peerVersion()
+    return conn.send(encodeFrame(version, msgTypeResponse, flags, bs))
+}
+
+// keepalive pings the client when the connection has been quiet, closes it when the client
+// does not answer, and reaps it once no request came for the idle timeout. An idle connection
+// gets a goAway first, it is closed on the next tick if the client did not use it meanwhile.
+func (conn *tcpConn)keepalive() {
+    idleTimeout := conn.srv.opts.idleTimeout
+    tick := idleTimeout / 2
+    if conn.heartbeat != nil && (tick <= 0 || conn.heartbeat.tick() < tick) {
+        tick = conn.heartbeat.tick()
+    }
+    if tick < minHeartbeatTick {
+        tick = minHeartbeatTick
+    }
+    ticker := time.NewTicker(tick)
+    defer ticker.Stop()
+
+    goAwaySent := false
+    for {
+        select {
+        case now := <- ticker.C:
+            version, flags := conn.peerVersion()
+            // v1 clients can not answer a ping.
+            if conn.heartbeat != nil && version >= ProtocolV2 {
+                ping, dead := conn.heartbeat.check(now)
+                if dead {
+                    logx.Log(logx.Kv("message", "heartbeat timeout"), logx.Kv("server", conn.srv.Name()), logx.Kv("ip", conn.ip), logx.Kv("port", conn.port))
+                    conn.close()
+                    return
+                }
+                if ping {
+                    conn.send(encodeFrame(version, msgTypePing, flags, nil))
+                }
+            }
+            if idleTimeout <= 0 {
+                continue
+            }
+            if !conn.idle(now, idleTimeout) {
+                goAwaySent = false
+                continue
+            }
+            if goAwaySent {
+                conn.close()
+                return
+            }
+            conn.goAway()
+            goAwaySent = true
+        case <- conn.done:
+            return
+        }
+    }
+}
+
+// idle reports whether nothing is in flight and the last request is older than timeout.
+func (conn *tcpConn)idle(now time.Time, timeout time.Duration) bool {
+    conn.mu.Lock()
+    busy := len(conn.cancels) > 0 || len(conn.streams) > 0
+    conn.mu.Unlock()
+    return !busy && now.UnixNano()-atomic.LoadInt64(&conn.lastActive) >= int64(timeout)
+}
+
+// drained reports whether no request is in flight and nothing was read for drainIdleTimeout
+// since the goAway.
+func (conn *tcpConn)drained(now, goAwayAt time.Time) bool {
+    if atomic.LoadInt32(&conn.invokeNum) > 0 {
+        return false
+    }
+    last := atomic.LoadInt64(&conn.lastActive)
+    if last < goAwayAt.UnixNano() {
+        last = goAwayAt.UnixNano()
+    }
+    return now.UnixNano()-last >= int64(drainIdleTimeout)
+}
+
+func (conn *tcpConn)active() {
+    atomic.StoreInt64(&conn.lastActive, time.Now().UnixNano())
+}
+
+// handshake completes the TLS handshake before the first read, so the client certificate is
+// known to every request.
+func (conn *tcpConn)handshake() error {
+    tlsConn, ok := conn.rw.(*tls.Conn)
+    if !ok {
+        return nil
+    }
+    tlsConn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+    if err := tlsConn.Handshake(); err != nil {
+        return err
+    }
+    tlsConn.SetDeadline(time.Time{})
+    state := tlsConn.ConnectionState()
+    conn.peer.TLSState = &state
+    return nil
+}
+
+// context carries what the handler of req may need from the connection.
+func (conn *tcpConn)context(ctx context.Context, req *Request) context.Context {
+    ctx = NewIncomingContext(ctx, req.Meta)
+    return newPeerContext(ctx, conn.peer)
+}
+
 func (conn *tcpConn)close() {
+    conn.closeOnce.Do(conn.doClose)
+}
+
+func (conn *tcpConn)doClose() {
+    close(conn.done)
     conn.srv.removeConn(conn)
+    conn.writer.close()
     conn.rw.Close()
+
+    conn.mu.Lock()
+    streams := conn.streams
+    conn.streams = make(map[int64]*serverStream)
+    cancels := conn.cancels
+    conn.cancels = make(map[int64]context.CancelFunc)
+    conn.mu.Unlock()
+    for _, stream := range streams {
+        stream.finish(ErrStreamClosed)
+        stream.cancel()
+    }
+    for _, cancel := range cancels {
+        cancel()
+    }
 }
 
 const state_full = 1
 const state_need_read = 2
 const state_err = -1
 
-func readBody(bs []byte) ([]byte, int, int) {
-    if len(bs) <= 4 {
-        return nil, 0, state_need_read
+// dispatch unpacks the request in the read loop, so frames of a stream keep their order,
+// and runs unary calls in their own goroutine.
+func (conn *tcpConn)dispatch(f *frame) {
+    atomic.StoreUint32(&conn.version, uint32(f.version))
+    atomic.StoreUint32(&conn.flags, uint32(f.flags))
+    if f.version >= ProtocolV2 {
+        switch f.msgType {
+        case msgTypeRequest, msgTypeCancel:
+        case msgTypePing:
+            conn.send(encodeFrame(f.version, msgTypePong, f.flags & flagChecksum, nil))
+            return
+        default:
+            return
+        }
     }
-    n := int(binary.LittleEndian.Uint32(bs))
-    if n <= 4 {
-        return nil, 0, state_err
+    conn.active()
+    req, err := conn.srv.protocol.UnPacketRequest(f.payload)
+    if err != nil {
+        logx.Log(logx.Kv("message", "unpacket failed"), logx.Kv("protocol", conn.srv.protocol.Name()), logx.Kv("error", err))
+        return
     }
-    if n <= len(bs) {
-        return bs[:n], n, state_full
+    if f.msgType == msgTypeCancel {
+        conn.cancel(req.RequestId)
+        return
+    }
+    if req.StreamFrame != StreamFrame_STREAM_NONE {
+        conn.handleStream(req)
+        return
+    }
+    // the cancel func is registered before the goroutine starts so an early cancel frame finds it.
+    ctx, cancel := context.WithCancel(context.TODO())
+    conn.mu.Lock()
+    conn.cancels[req.RequestId] = cancel
+    conn.mu.Unlock()
+    // in flight from now until the response is written, queued for a tick as well.
+    atomic.AddInt32(&conn.invokeNum, 1)
+    go conn.invoke(ctx, req)
+}
+
+func (conn *tcpConn)cancel(id int64) {
+    conn.mu.Lock()
+    cancel, ok := conn.cancels[id]
+    delete(conn.cancels, id)
+    conn.mu.Unlock()
+    if ok {
+        conn.active()
+        cancel()
+    }
+}
+
+func (conn *tcpConn)handleStream(req *Request) {
+    if req.StreamFrame == StreamFrame_STREAM_OPEN {
+        conn.openStream(req)
+        return
+    }
+    conn.mu.Lock()
+    stream, ok := conn.streams[req.RequestId]
+    conn.mu.Unlock()
+    if !ok {
+        return
+    }
+    switch req.StreamFrame {
+    case StreamFrame_STREAM_DATA:
+        if err := stream.push(req.Body); err != nil {
+            stream.finish(err)
+            stream.cancel()
+            conn.sendStreamFrame(stream.id, StreamFrame_STREAM_RESET, nil, 0, err)
+        }
+    case StreamFrame_STREAM_HALF_CLOSE:
+        stream.finish(io.EOF)
+    case StreamFrame_STREAM_RESET:
+        stream.finish(ErrStreamReset)
+        stream.cancel()
+    case StreamFrame_STREAM_WINDOW_UPDATE:
+        stream.addWindow(req.Window)
+    }
+}
+
+func (conn *tcpConn)openStream(req *Request) {
+    if conn.srv.opts.streamDispatcher == nil {
+        conn.sendStreamFrame(req.RequestId, StreamFrame_STREAM_RESET, nil, 0, ErrMethodNotFound)
+        return
+    }
+    enc := GetEncoder(Meta(req.Meta).Get(EncodeType))
+    if enc == nil {
+        conn.sendStreamFrame(req.RequestId, StreamFrame_STREAM_RESET, nil, 0, uerror.ErrEncoderNotFound)
+        return
+    }
+    ctx := conn.context(context.TODO(), req)
+    var cancel context.CancelFunc
+    if req.Timeout > 0 {
+        ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Timeout) * time.Millisecond)
+    } else {
+        ctx, cancel = context.WithCancel(ctx)
+    }
+    id := req.RequestId
+    stream := &serverStream{
+        streamCore: newStreamCore(ctx, id, enc, func(frame StreamFrame, body []byte, window uint32) error {
+            return conn.sendStreamFrame(id, frame, body, window, nil)
+        }),
+        cancel: cancel,
+    }
+    conn.mu.Lock()
+    if _, ok := conn.streams[id]; ok {
+        conn.mu.Unlock()
+        cancel()
+        return
     }
-    return nil, 0, state_need_read
+    conn.streams[id] = stream
+    conn.mu.Unlock()
+    atomic.AddInt32(&conn.invokeNum, 1)
+    go conn.runStream(req, stream)
 }
 
-func (conn *tcpConn)invoke(body []byte) {
+func (conn *tcpConn)runStream(req *Request, stream *serverStream) {
     defer logx.Recover()
+    defer func() {
+        conn.mu.Lock()
+        delete(conn.streams, stream.id)
+        conn.mu.Unlock()
+        conn.active()
+        stream.cancel()
+        atomic.AddInt32(&conn.invokeNum, -1)
+    }()
 
-    req, err := conn.srv.protocol.UnPacketRequest(body)
-    if err != nil {
-        logx.Log(logx.Kv("message", "unpacket failed"), logx.Kv("protocol", conn.srv.protocol.Name()), logx.Kv("error", err))
+    start := time.Now()
+    select {
+    case <- stream.ctx.Done():
+        conn.sendStreamFrame(stream.id, StreamFrame_STREAM_RESET, nil, 0, uerror.ErrRequestFull)
         return
+    case conn.srv.opts.tick <- struct{}{}:
+        defer func() {
+            <- conn.srv.opts.tick
+        }()
+    }
+
+    err := conn.srv.handleStream(req.Method, stream)
+    if stream.ctx.Err() != nil && err == nil {
+        err = stream.ctx.Err()
+    }
+    conn.sendStreamFrame(stream.id, StreamFrame_STREAM_HALF_CLOSE, nil, 0, err)
+
+    code, desc := int32(200), "ok"
+    if err != nil {
+        werr := uerror.ParseError(err)
+        code, desc = werr.Code, werr.ErrMsg
+    }
+    interval := time.Now().Sub(start)
+    logx.Log("stream call time", logx.Kv("protocol", conn.srv.protocol.Name()), logx.Kv("server", conn.srv.Name()), logx.Kv("method", req.Method), logx.Kv("code", code), logx.Kv("status", desc), logx.Kv("spend", interval.String()))
+}
+
+func (conn *tcpConn)sendStreamFrame(id int64, frame StreamFrame, body []byte, window uint32, err error) error {
+    resp := &Response{
+        RequestId: id,
+        Body: body,
+        Code: 200,
+        CodeStatus: "ok",
+        StreamFrame: frame,
+        Window: window,
     }
+    if err != nil {
+        werr := uerror.ParseError(err)
+        resp.Code = werr.Code
+        resp.CodeStatus = werr.ErrMsg
+    }
+    return conn.sendResponse(resp)
+}
+
+func (conn *tcpConn)invoke(ctx context.Context, req *Request) {
+    defer atomic.AddInt32(&conn.invokeNum, -1)
+    defer logx.Recover()
+    defer conn.cancel(req.RequestId)
 
     var resp *Response
     meta := Meta(req.Meta)
@@ -261,10 +722,10 @@ func (conn *tcpConn)invoke(body []byte) {
         logx.Log("request call time", logx.Kv("protocol", conn.srv.protocol.Name()), logx.Kv("server", conn.srv.Name()), logx.Kv("method", req.Method), logx.Kv("interval", int32(interval/time.Millisecond)), logx.Kv("code", code), logx.Kv("status", desc), logx.Kv("encoder", encName), logx.Kv("spend", interval.String()))
     }()
 
-    ctx := NewOutgoingContext(context.TODO(), req.Meta)
+    ctx = conn.context(ctx, req)
     var cancel context.CancelFunc
-    if conn.srv.opts.invokeTimeout > 0 {
-        ctx, cancel = context.WithTimeout(ctx, conn.srv.opts.invokeTimeout)
+    if timeout := conn.srv.invokeTimeout(req); timeout > 0 {
+        ctx, cancel = context.WithTimeout(ctx, timeout)
     }
     if cancel != nil {
         defer cancel()
@@ -283,34 +744,60 @@ func (conn *tcpConn)invoke(body []byte) {
     if enc == nil && resp == nil {
         resp = GetResponse(req, nil, uerror.ErrEncoderNotFound)
     }
+    if resp == nil {
+        body, err := decompress(meta.Get(reqCompressKey), conn.srv.opts.maxMessageSize, req.Body)
+        if err != nil {
+            resp = GetResponse(req, nil, err)
+        }
+        req.Body = body
+    }
 
     if resp == nil {
-        respChan := make(chan *Response)
+        respChan := make(chan *Response, 1)
         go func() {
             defer logx.Recover()
-            bin, err := conn.srv.dispatcher(ctx, conn.srv.impl, req, enc)
+            bin, err := conn.srv.handle(ctx, req, enc)
             resp := GetResponse(req, bin, err)
             respChan <- resp
         }()
 
         select {
         case <- ctx.Done():
-            resp = GetResponse(req, nil, uerror.ErrRequestFull)
+            resp = GetResponse(req, nil, uerror.ErrRequestTimeout)
         case resp = <- respChan:
         }
-        close(respChan)
     }
 
-    bs, err := conn.srv.protocol.PacketResponse(resp)
-    if err != nil {
+    if ctx.Err() == context.Canceled {
+        // the client is gone, nobody reads the response.
+        resp = GetResponse(req, nil, uerror.ErrRequestCanceled)
         return
     }
-    err = conn.send(bs)
+    conn.srv.compressResponse(meta.Get(acceptCompressKey), resp)
+    conn.sendResponse(resp)
 }
 
 func (conn *tcpConn)send(body []byte) error {
-    _, err := conn.rw.Write(body)
-    return err
+    return conn.writer.write(body)
+}
+
+// compressResponse compresses the body with the compressor the client accepts, the meta is
+// copied as it is shared with the request.
+func (srv *TcpServer)compressResponse(name string, resp *Response) {
+    if resp.Code != 200 || GetCompressor(name) == nil {
+        return
+    }
+    body, used, err := compress(name, srv.opts.compressThreshold, resp.Body)
+    if err != nil || used == "" {
+        return
+    }
+    meta := make(map[string]string, len(resp.Meta)+1)
+    for k, v := range resp.Meta {
+        meta[k] = v
+    }
+    meta[respCompressKey] = used
+    resp.Meta = meta
+    resp.Body = body
 }
 
 func GetResponse(req *Request, bs []byte, err error) *Response {
@@ -0,0 +1,358 @@
+package wrpc_go
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "sync"
+
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+)
+
+const defaultStreamWindow = 64 * 1024
+
+var (
+    ErrStreamClosed = fmt.Errorf("rpc: stream is closed")
+    ErrStreamReset  = uerror.NewError(499, "stream reset")
+    // ErrStreamWindowExceeded resets a stream whose peer sent data beyond the granted window.
+    ErrStreamWindowExceeded = uerror.NewError(502, "stream window exceeded")
+)
+
+type StreamDispatcher func(impl interface{}, method string, stream ServerStream) error
+
+type ServerStream interface {
+    Context() context.Context
+    SendMsg(m interface{}) error
+    RecvMsg(m interface{}) error
+}
+
+type ClientStream interface {
+    Context() context.Context
+    SendMsg(m interface{}) error
+    RecvMsg(m interface{}) error
+    // CloseSend tells the server no more message will be sent, messages can still be received.
+    CloseSend() error
+}
+
+// streamCore holds the state shared by both ends of a stream: received messages waiting
+// for RecvMsg, the receive window granted to the peer and the send window granted by the peer.
+// Frames are written through send.
+type streamCore struct {
+    id   int64
+    ctx  context.Context
+    enc  Encoder
+    send func(frame StreamFrame, body []byte, window uint32) error
+
+    mu         sync.Mutex
+    recvBuf    [][]byte
+    recvErr    error
+    recvSignal chan struct{}
+    consumed   int64
+    recvWindow int64
+    sendWindow int64
+    sendSignal chan struct{}
+    sendClosed bool
+}
+
+func newStreamCore(ctx context.Context, id int64, enc Encoder, send func(StreamFrame, []byte, uint32) error) *streamCore {
+    return &streamCore{
+        id: id,
+        ctx: ctx,
+        enc: enc,
+        send: send,
+        recvSignal: make(chan struct{}, 1),
+        recvWindow: defaultStreamWindow,
+        sendWindow: defaultStreamWindow,
+        sendSignal: make(chan struct{}, 1),
+    }
+}
+
+func notify(ch chan struct{}) {
+    select {
+    case ch <- struct{}{}:
+    default:
+    }
+}
+
+func (s *streamCore)Context() context.Context {
+    return s.ctx
+}
+
+// push buffers a received message. The peer may only send while it has some window left, like
+// sendData does, so a message arriving on an exhausted window fails with ErrStreamWindowExceeded.
+func (s *streamCore)push(body []byte) error {
+    s.mu.Lock()
+    if s.recvWindow <= 0 {
+        s.mu.Unlock()
+        return ErrStreamWindowExceeded
+    }
+    s.recvWindow -= int64(len(body))
+    if s.recvErr == nil {
+        s.recvBuf = append(s.recvBuf, body)
+    }
+    s.mu.Unlock()
+    notify(s.recvSignal)
+    return nil
+}
+
+// finish ends the receiving side, RecvMsg returns err once the buffered messages are read.
+func (s *streamCore)finish(err error) {
+    s.mu.Lock()
+    if s.recvErr == nil {
+        s.recvErr = err
+    }
+    s.mu.Unlock()
+    notify(s.recvSignal)
+    notify(s.sendSignal)
+}
+
+func (s *streamCore)addWindow(n uint32) {
+    s.mu.Lock()
+    s.sendWindow += int64(n)
+    s.mu.Unlock()
+    notify(s.sendSignal)
+}
+
+func (s *streamCore)recv() ([]byte, error) {
+    for {
+        s.mu.Lock()
+        if len(s.recvBuf) > 0 {
+            body := s.recvBuf[0]
+            s.recvBuf[0] = nil
+            s.recvBuf = s.recvBuf[1:]
+            s.consumed += int64(len(body))
+            var update int64
+            if s.consumed >= defaultStreamWindow/2 && s.recvErr == nil {
+                update = s.consumed
+                s.consumed = 0
+                s.recvWindow += update
+            }
+            s.mu.Unlock()
+            if update > 0 {
+                s.send(StreamFrame_STREAM_WINDOW_UPDATE, nil, uint32(update))
+            }
+            return body, nil
+        }
+        if s.recvErr != nil {
+            err := s.recvErr
+            s.mu.Unlock()
+            return nil, err
+        }
+        s.mu.Unlock()
+
+        select {
+        case <- s.recvSignal:
+        case <- s.ctx.Done():
+            s.finish(s.ctx.Err())
+        }
+    }
+}
+
+// sendData blocks until the peer has granted some window. A message may be larger than the
+// remaining window, which then goes negative until enough updates arrive.
+func (s *streamCore)sendData(body []byte) error {
+    for {
+        s.mu.Lock()
+        if s.sendClosed {
+            s.mu.Unlock()
+            return ErrStreamClosed
+        }
+        if s.recvErr != nil && s.recvErr != io.EOF {
+            err := s.recvErr
+            s.mu.Unlock()
+            return err
+        }
+        if s.sendWindow > 0 {
+            s.sendWindow -= int64(len(body))
+            s.mu.Unlock()
+            return s.send(StreamFrame_STREAM_DATA, body, 0)
+        }
+        s.mu.Unlock()
+
+        select {
+        case <- s.sendSignal:
+        case <- s.ctx.Done():
+            return s.ctx.Err()
+        }
+    }
+}
+
+func (s *streamCore)closeSend() bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.sendClosed {
+        return false
+    }
+    s.sendClosed = true
+    return true
+}
+
+func (s *streamCore)SendMsg(m interface{}) error {
+    bs, err := s.enc.Encode(m)
+    if err != nil {
+        return err
+    }
+    return s.sendData(bs)
+}
+
+func (s *streamCore)RecvMsg(m interface{}) error {
+    bs, err := s.recv()
+    if err != nil {
+        return err
+    }
+    return s.enc.Decode(bs, m)
+}
+
+type serverStream struct {
+    *streamCore
+    cancel context.CancelFunc
+}
+
+type clientStream struct {
+    *streamCore
+    conn *clientConn
+    done chan struct{}
+    once sync.Once
+}
+
+func (cs *clientStream)CloseSend() error {
+    if !cs.closeSend() {
+        return nil
+    }
+    return cs.send(StreamFrame_STREAM_HALF_CLOSE, nil, 0)
+}
+
+// end is called once the stream is over on the client side, the reason is kept for RecvMsg.
+func (cs *clientStream)end(err error) {
+    cs.once.Do(func() {
+        cs.finish(err)
+        cs.closeSend()
+        cs.conn.removeStream(cs.id)
+        close(cs.done)
+    })
+}
+
+func (cs *clientStream)watch() {
+    select {
+    case <- cs.done:
+    case <- cs.ctx.Done():
+        cs.send(StreamFrame_STREAM_RESET, nil, 0)
+        cs.end(cs.ctx.Err())
+    }
+}
+
+func (cs *clientStream)handle(resp *Response) {
+    switch resp.StreamFrame {
+    case StreamFrame_STREAM_DATA:
+        if err := cs.push(resp.Body); err != nil {
+            cs.send(StreamFrame_STREAM_RESET, nil, 0)
+            cs.end(err)
+        }
+    case StreamFrame_STREAM_WINDOW_UPDATE:
+        cs.addWindow(resp.Window)
+    case StreamFrame_STREAM_HALF_CLOSE, StreamFrame_STREAM_RESET:
+        var err error = io.EOF
+        if resp.Code > 0 && resp.Code != 200 {
+            err = uerror.NewError(resp.Code, resp.CodeStatus)
+        } else if resp.StreamFrame == StreamFrame_STREAM_RESET {
+            err = ErrStreamReset
+        }
+        cs.end(err)
+    }
+}
+
+// NewStream opens a stream to method on an endpoint chosen like Invoke does, the meta of opt is
+// sent with the open frame. The stream lives until the server returns, ctx is done or the
+// connection is closed.
+func (client *Client)NewStream(ctx context.Context, encName, addr, method string, opt ...map[string]string) (ClientStream, error) {
+    if client.isClosed() {
+        return nil, ErrClientClosed
+    }
+    metadata := make(Meta)
+    if md, ok := FromOutgoingContext(ctx); ok {
+        for k, v := range md {
+            metadata[k] = v
+        }
+    }
+    for _, md := range opt {
+        for k, v := range md {
+            metadata[k] = v
+        }
+    }
+    if encName == "" {
+        encName = client.opts.encodeType
+    }
+    call := &ClientCall{
+        Method: method,
+        Addr: addr,
+        EncodeType: encName,
+        Meta: metadata,
+    }
+    if client.streamInterceptor == nil {
+        return client.newStream(ctx, call)
+    }
+    return client.streamInterceptor(ctx, call, client.newStream)
+}
+
+func (client *Client)newStream(ctx context.Context, call *ClientCall) (ClientStream, error) {
+    enc := GetEncoder(call.EncodeType)
+    if enc == nil {
+        return nil, uerror.ErrEncoderNotFound
+    }
+    metadata := make(Meta, len(call.Meta)+1)
+    for k, v := range call.Meta {
+        metadata[k] = v
+    }
+    metadata.Set(EncodeType, call.EncodeType)
+
+    key, findType := findKey(call.Addr, metadata)
+    connect, err := client.connector(key, findType, nil)
+    if err != nil {
+        return nil, err
+    }
+    conn, err := connect.getConn()
+    if err != nil {
+        return nil, err
+    }
+    call.Addr = connect.addr
+
+    id := nextRequestId()
+    cs := &clientStream{
+        conn: conn,
+        done: make(chan struct{}),
+    }
+    cs.streamCore = newStreamCore(ctx, id, enc, func(frame StreamFrame, body []byte, window uint32) error {
+        req := &Request{
+            RequestId: id,
+            Body: body,
+            StreamFrame: frame,
+            Window: window,
+        }
+        bs, err := client.packRequest(req)
+        if err != nil {
+            return err
+        }
+        return conn.write(bs)
+    })
+    if !conn.addStream(cs) {
+        return nil, ErrStreamClosed
+    }
+
+    req := &Request{
+        RequestId: id,
+        Method: call.Method,
+        Meta: metadata,
+        StreamFrame: StreamFrame_STREAM_OPEN,
+        Timeout: remainingTimeout(ctx),
+    }
+    bs, err := client.packRequest(req)
+    if err == nil {
+        err = conn.writeWait(bs)
+    }
+    if err != nil {
+        cs.end(err)
+        return nil, err
+    }
+    go cs.watch()
+    return cs, nil
+}
@@ -0,0 +1,102 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "strings"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+)
+
+// streamDispatch serves the streams of the tests: Meta sends the incoming meta,
+// Slow waits for the milliseconds of the first message before reading the others.
+func streamDispatch(impl interface{}, method string, stream wrpc_go.ServerStream) error {
+    switch method {
+    case "Meta":
+        meta, _ := wrpc_go.FromIncomingContext(stream.Context())
+        return stream.SendMsg(meta)
+    case "Slow":
+        var ms int
+        if err := stream.RecvMsg(&ms); err != nil {
+            return err
+        }
+        time.Sleep(time.Duration(ms) * time.Millisecond)
+        for {
+            var s string
+            if err := stream.RecvMsg(&s); err == io.EOF {
+                return nil
+            } else if err != nil {
+                return err
+            }
+        }
+    }
+    return wrpc_go.ErrMethodNotFound
+}
+
+func TestStreamMeta(t *testing.T) {
+    var methods []string
+    ts := startServer(t, wrpc_go.WithServerOptionStreamDispatcher(streamDispatch),
+        wrpc_go.WithServerOptionStreamInterceptors(func(stream wrpc_go.ServerStream, info *wrpc_go.ServerInfo, handler wrpc_go.StreamHandler) error {
+            methods = append(methods, info.Method)
+            return handler(stream)
+        }))
+    client := newClient(t, ts, wrpc_go.WithClientOptionStreamInterceptors(func(ctx context.Context, call *wrpc_go.ClientCall, streamer wrpc_go.ClientStreamer) (wrpc_go.ClientStream, error) {
+        call.Meta.Set("from-interceptor", "1")
+        return streamer(ctx, call)
+    }))
+
+    ctx := wrpc_go.NewOutgoingContext(context.Background(), wrpc_go.Meta{"from-ctx": "1"})
+    stream, err := client.NewStream(ctx, "json", "", "Meta", map[string]string{"from-opt": "1"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    meta := map[string]string{}
+    if err := stream.RecvMsg(&meta); err != nil {
+        t.Fatal(err)
+    }
+    for _, key := range []string{"from-ctx", "from-opt", "from-interceptor"} {
+        if meta[key] != "1" {
+            t.Fatalf("%s is missing from the meta of the stream: %v", key, meta)
+        }
+    }
+    if err := stream.RecvMsg(&meta); err != io.EOF {
+        t.Fatalf("got %v, want the end of the stream", err)
+    }
+    if len(methods) != 1 || methods[0] != "Meta" {
+        t.Fatalf("server interceptor saw %v", methods)
+    }
+}
+
+func TestStreamFlowControl(t *testing.T) {
+    ts := startServer(t, wrpc_go.WithServerOptionStreamDispatcher(streamDispatch))
+    client := newClient(t, ts)
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+    defer cancel()
+    stream, err := client.NewStream(ctx, "json", "", "Slow")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := stream.SendMsg(300); err != nil {
+        t.Fatal(err)
+    }
+    // two messages use up the window of the server, the third waits for it to read.
+    msg := strings.Repeat("a", 32 * 1024)
+    start := time.Now()
+    for i := 0; i < 6; i++ {
+        if err := stream.SendMsg(msg); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if elapsed := time.Since(start); elapsed < 200 * time.Millisecond {
+        t.Fatalf("sent 192KB in %v, the window of the server was not respected", elapsed)
+    }
+    stream.CloseSend()
+    var out json.RawMessage
+    if err := stream.RecvMsg(&out); err != io.EOF {
+        t.Fatalf("got %v, want the end of the stream", err)
+    }
+}
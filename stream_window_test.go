@@ -0,0 +1,33 @@
+package wrpc_go
+
+import (
+    "context"
+    "testing"
+)
+
+func TestStreamWindowExceeded(t *testing.T) {
+    var updates []uint32
+    s := newStreamCore(context.Background(), 1, GetEncoder("json"), func(frame StreamFrame, body []byte, window uint32) error {
+        updates = append(updates, window)
+        return nil
+    })
+    msg := make([]byte, defaultStreamWindow/2)
+    for i := 0; i < 2; i++ {
+        if err := s.push(msg); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := s.push(msg); err != ErrStreamWindowExceeded {
+        t.Fatalf("got %v, want ErrStreamWindowExceeded", err)
+    }
+
+    if _, err := s.recv(); err != nil {
+        t.Fatal(err)
+    }
+    if len(updates) != 1 || updates[0] != defaultStreamWindow/2 {
+        t.Fatalf("window updates %v", updates)
+    }
+    if err := s.push(msg); err != nil {
+        t.Fatalf("push within the updated window: %v", err)
+    }
+}
@@ -0,0 +1,182 @@
+package wrpc_go
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "sync"
+    "time"
+
+    "github.com/wukong-cloud/wrpc-go/util/logx"
+)
+
+// TLSConfig loads the certificates from files, which are read again when they change on disk
+// so rotated certificates are used by the next handshakes without a restart.
+// On the server CAFile holds the CAs of the client certificates, on the client the CAs of the
+// server, the system roots are used when it is empty.
+type TLSConfig struct {
+    CertFile   string `yaml:"cert-file"`
+    KeyFile    string `yaml:"key-file"`
+    CAFile     string `yaml:"ca-file"`
+    // ServerName is checked against the server certificate, the host of the address by default.
+    ServerName string `yaml:"server-name"`
+    // ClientAuth is one of none, request, require-any, verify-if-given and require. It is
+    // require when a CA file is set and none otherwise.
+    ClientAuth string `yaml:"client-auth"`
+    InsecureSkipVerify bool `yaml:"insecure-skip-verify"`
+}
+
+const (
+    tlsReloadInterval   = time.Second
+    tlsHandshakeTimeout = 10 * time.Second
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+    "none":            tls.NoClientCert,
+    "request":         tls.RequestClientCert,
+    "require-any":     tls.RequireAnyClientCert,
+    "verify-if-given": tls.VerifyClientCertIfGiven,
+    "require":         tls.RequireAndVerifyClientCert,
+}
+
+// certReloader holds the certificate and CA pool of a TLSConfig, it checks at most once per
+// tlsReloadInterval whether the files changed.
+type certReloader struct {
+    conf      *TLSConfig
+    mu        sync.Mutex
+    cert      *tls.Certificate
+    pool      *x509.CertPool
+    modTime   time.Time
+    checkedAt time.Time
+}
+
+func newCertReloader(conf *TLSConfig) (*certReloader, error) {
+    r := &certReloader{conf: conf}
+    if err := r.load(); err != nil {
+        return nil, err
+    }
+    r.checkedAt = time.Now()
+    return r, nil
+}
+
+func (r *certReloader)load() error {
+    var cert *tls.Certificate
+    if r.conf.CertFile != "" || r.conf.KeyFile != "" {
+        c, err := tls.LoadX509KeyPair(r.conf.CertFile, r.conf.KeyFile)
+        if err != nil {
+            return err
+        }
+        cert = &c
+    }
+    var pool *x509.CertPool
+    if r.conf.CAFile != "" {
+        pem, err := ioutil.ReadFile(r.conf.CAFile)
+        if err != nil {
+            return err
+        }
+        pool = x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(pem) {
+            return fmt.Errorf("tls: no certificate found in %s", r.conf.CAFile)
+        }
+    }
+    r.cert, r.pool, r.modTime = cert, pool, r.lastModified()
+    return nil
+}
+
+func (r *certReloader)lastModified() time.Time {
+    var last time.Time
+    for _, file := range []string{r.conf.CertFile, r.conf.KeyFile, r.conf.CAFile} {
+        if file == "" {
+            continue
+        }
+        if info, err := os.Stat(file); err == nil && info.ModTime().After(last) {
+            last = info.ModTime()
+        }
+    }
+    return last
+}
+
+// get returns the current certificate and CA pool, a rotation that fails to load keeps the old ones.
+func (r *certReloader)get() (*tls.Certificate, *x509.CertPool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if time.Since(r.checkedAt) >= tlsReloadInterval {
+        r.checkedAt = time.Now()
+        if !r.lastModified().Equal(r.modTime) {
+            if err := r.load(); err != nil {
+                logx.Log(logx.Kv("message", "reload tls certificate failed"), logx.Kv("cert", r.conf.CertFile), logx.Kv("error", err))
+            }
+        }
+    }
+    return r.cert, r.pool
+}
+
+func newServerTLSConfig(conf *TLSConfig) (*tls.Config, error) {
+    clientAuth := tls.NoClientCert
+    if conf.CAFile != "" {
+        clientAuth = tls.RequireAndVerifyClientCert
+    }
+    if conf.ClientAuth != "" {
+        auth, ok := clientAuthTypes[conf.ClientAuth]
+        if !ok {
+            return nil, fmt.Errorf("tls: unknown client-auth %q", conf.ClientAuth)
+        }
+        clientAuth = auth
+    }
+    r, err := newCertReloader(conf)
+    if err != nil {
+        return nil, err
+    }
+    if r.cert == nil {
+        return nil, fmt.Errorf("tls: the server needs a certificate")
+    }
+    return &tls.Config{
+        MinVersion: tls.VersionTLS12,
+        GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+            cert, pool := r.get()
+            return &tls.Config{
+                MinVersion: tls.VersionTLS12,
+                Certificates: []tls.Certificate{*cert},
+                ClientCAs: pool,
+                ClientAuth: clientAuth,
+            }, nil
+        },
+    }, nil
+}
+
+// newClientTLSConfig returns the config of each dial, the CA pool can not be swapped in a
+// config in use so a fresh one is made every time.
+func newClientTLSConfig(conf *TLSConfig) (func() *tls.Config, error) {
+    r, err := newCertReloader(conf)
+    if err != nil {
+        return nil, err
+    }
+    return func() *tls.Config {
+        cert, pool := r.get()
+        config := &tls.Config{
+            MinVersion: tls.VersionTLS12,
+            RootCAs: pool,
+            ServerName: conf.ServerName,
+            InsecureSkipVerify: conf.InsecureSkipVerify,
+        }
+        if cert != nil {
+            config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+                return cert, nil
+            }
+        }
+        return config
+    }, nil
+}
+
+// PeerCertificateFromContext returns the verified certificate of the client in a handler,
+// it is only set with mutual TLS.
+func PeerCertificateFromContext(ctx context.Context) (*x509.Certificate, bool) {
+    p, ok := PeerFromContext(ctx)
+    if !ok || p.TLSState == nil || len(p.TLSState.VerifiedChains) == 0 {
+        return nil, false
+    }
+    return p.TLSState.PeerCertificates[0], true
+}
@@ -0,0 +1,74 @@
+package wrpc_go
+
+import (
+    "context"
+    "crypto/tls"
+    "net"
+    "strings"
+    "time"
+)
+
+// unixScheme prefixes the path of a unix domain socket, e.g. "unix:///var/run/app.sock".
+const unixScheme = "unix://"
+
+// Dialer opens the connections of a client, addr is the endpoint as configured.
+type Dialer func(ctx context.Context, addr string) (net.Conn, error)
+
+// splitAddr returns the network and address to listen on or dial.
+func splitAddr(addr string) (string, string) {
+    if strings.HasPrefix(addr, unixScheme) {
+        return "unix", addr[len(unixScheme):]
+    }
+    return "tcp", addr
+}
+
+func (opts *ServerOptions)listen() (net.Listener, error) {
+    if opts.listener != nil {
+        return opts.listener, nil
+    }
+    return net.Listen(splitAddr(opts.addr))
+}
+
+func (client *Client)dial(addr string) (net.Conn, error) {
+    if client.tlsErr != nil {
+        return nil, client.tlsErr
+    }
+    dialer := client.opts.dialer
+    if dialer == nil {
+        dialer = func(ctx context.Context, addr string) (net.Conn, error) {
+            var d net.Dialer
+            network, address := splitAddr(addr)
+            return d.DialContext(ctx, network, address)
+        }
+    }
+    ctx := context.Background()
+    if timeout := client.opts.connectTimeout; timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+        defer cancel()
+    }
+    rw, err := dialer(ctx, addr)
+    if err != nil || client.tlsConfig == nil {
+        return rw, err
+    }
+    return clientHandshake(rw, addr, client.tlsConfig())
+}
+
+// clientHandshake runs TLS over rw, the server name defaults to the host of addr as in tls.Dial.
+func clientHandshake(rw net.Conn, addr string, config *tls.Config) (net.Conn, error) {
+    if config.ServerName == "" {
+        _, address := splitAddr(addr)
+        if host, _, err := net.SplitHostPort(address); err == nil {
+            config = config.Clone()
+            config.ServerName = host
+        }
+    }
+    conn := tls.Client(rw, config)
+    conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+    if err := conn.Handshake(); err != nil {
+        rw.Close()
+        return nil, err
+    }
+    conn.SetDeadline(time.Time{})
+    return conn, nil
+}
@@ -0,0 +1,97 @@
+package wrpc_go_test
+
+import (
+    "bytes"
+    "context"
+    "io"
+    "net"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/wrpctest"
+)
+
+func TestConnectTimeout(t *testing.T) {
+    ts := startServer(t)
+    client := newClient(t, ts,
+        wrpc_go.WithClientOptionConnectTimeout(50 * time.Millisecond),
+        wrpc_go.WithClientOptionDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+            <- ctx.Done()
+            return nil, ctx.Err()
+        }))
+
+    start := time.Now()
+    if _, err := invoke(client, context.Background(), "Echo", `"a"`); err == nil {
+        t.Fatal("expected the dial to time out")
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("dial took %v", elapsed)
+    }
+}
+
+func TestSlowDialDoesNotBlockCalls(t *testing.T) {
+    ts := startServer(t)
+    dialing, release := make(chan struct{}), make(chan struct{})
+    dials := 0
+    client := newClient(t, ts,
+        wrpc_go.WithClientOptionMaxConn(2),
+        wrpc_go.WithClientOptionDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+            if dials++; dials > 1 {
+                close(dialing)
+                <- release
+            }
+            return ts.Listener.Dial(ctx, addr)
+        }))
+    defer close(release)
+
+    if _, err := invoke(client, context.Background(), "Echo", `"a"`); err != nil {
+        t.Fatal(err)
+    }
+    // the second call dials the second connection, which hangs.
+    go invoke(client, context.Background(), "Echo", `"a"`)
+    <- dialing
+
+    done := make(chan error, 1)
+    go func() {
+        _, err := invoke(client, context.Background(), "Echo", `"a"`)
+        done <- err
+    }()
+    select {
+    case err := <- done:
+        if err != nil {
+            t.Fatalf("call on the open connection: %v", err)
+        }
+    case <- time.After(time.Second):
+        t.Fatal("call waited for the dial of another connection")
+    }
+}
+
+func TestClientSendsV1ByDefault(t *testing.T) {
+    wrpc_go.LoadConfig(nil)
+    l := wrpctest.NewListener()
+    defer l.Close()
+    client := wrpc_go.NewClient(testServer,
+        wrpc_go.WithClientOptionAddr("memory"),
+        wrpc_go.WithClientOptionDialer(l.Dial))
+    defer client.Close()
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 200 * time.Millisecond)
+        defer cancel()
+        client.Invoke(ctx, "json", "", "Echo", []byte(`"a"`))
+    }()
+
+    conn, err := l.Accept()
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+    header := make([]byte, 4)
+    if _, err := io.ReadFull(conn, header); err != nil {
+        t.Fatal(err)
+    }
+    // a v1 frame starts with its length, a v2 frame with the magic a v1 server can not read.
+    if bytes.Equal(header, []byte("WRPC")) {
+        t.Fatal("the client sent a v2 frame without being configured to")
+    }
+}
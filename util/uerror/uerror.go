@@ -11,6 +11,9 @@ var (
     ErrRequestTimeout  = NewError(405, "request timeout")
     ErrRequestFull     = NewError(502, "request full")
     ErrEncoderNotFound = NewError(404, "encoder not found")
+    ErrCompressorNotFound = NewError(404, "compressor not found")
+    ErrMessageTooLarge = NewError(413, "message too large")
+    ErrRequestCanceled = NewError(499, "request canceled")
 )
 
 func NewError(code int32, errMsg string) error {
@@ -0,0 +1,175 @@
+package wrpc_go
+
+import (
+    "fmt"
+    "net"
+    "sync"
+    "time"
+)
+
+const (
+    defaultWriteQueueSize = 1024
+    maxWriteBatch         = 128
+    writerFlushTimeout    = time.Second
+)
+
+var ErrConnClosed = fmt.Errorf("rpc: connection is closed")
+
+// connWriter is the only goroutine writing to a connection. Frames are queued by any
+// number of callers and written in batches with one vectored write, so frames never
+// interleave on the socket. The queue is bounded, a full queue blocks the callers.
+type connWriter struct {
+    rw      net.Conn
+    queue   chan writeFrame
+    done    chan struct{}
+    exited  chan struct{}
+    once    sync.Once
+    onError func(err error)
+}
+
+// writeFrame is a queued frame, errc gets the result of its write when the caller waits for it.
+type writeFrame struct {
+    pkg  []byte
+    errc chan error
+}
+
+func newConnWriter(rw net.Conn, queueSize int, onError func(err error)) *connWriter {
+    if queueSize <= 0 {
+        queueSize = defaultWriteQueueSize
+    }
+    w := &connWriter{
+        rw: rw,
+        queue: make(chan writeFrame, queueSize),
+        done: make(chan struct{}),
+        exited: make(chan struct{}),
+        onError: onError,
+    }
+    go w.loop()
+    return w
+}
+
+// write queues pkg and returns, a later write error only closes the connection.
+func (w *connWriter)write(pkg []byte) error {
+    return w.enqueue(writeFrame{pkg: pkg})
+}
+
+// writeWait queues pkg and returns once it is written. An error means the peer did not get the
+// whole frame, whatever was queued before it.
+func (w *connWriter)writeWait(pkg []byte) error {
+    errc := make(chan error, 1)
+    if err := w.enqueue(writeFrame{pkg: pkg, errc: errc}); err != nil {
+        return err
+    }
+    select {
+    case err := <- errc:
+        return err
+    case <- w.exited:
+        // the frame may have been written just before the loop ended.
+        select {
+        case err := <- errc:
+            return err
+        default:
+            return ErrConnClosed
+        }
+    }
+}
+
+func (w *connWriter)enqueue(frame writeFrame) error {
+    select {
+    case <- w.done:
+        return ErrConnClosed
+    default:
+    }
+    select {
+    case w.queue <- frame:
+        return nil
+    case <- w.done:
+        return ErrConnClosed
+    }
+}
+
+// close stops the writer once the frames already queued are written, it waits at most
+// writerFlushTimeout, closing the connection afterwards unblocks a stuck write.
+func (w *connWriter)close() {
+    w.once.Do(func() {
+        close(w.done)
+    })
+    timer := time.NewTimer(writerFlushTimeout)
+    defer timer.Stop()
+    select {
+    case <- w.exited:
+    case <- timer.C:
+    }
+}
+
+func (w *connWriter)loop() {
+    defer close(w.exited)
+
+    batch := make([]writeFrame, 0, maxWriteBatch)
+    bufs := make(net.Buffers, 0, maxWriteBatch)
+    for {
+        select {
+        case frame := <- w.queue:
+            batch = append(batch[:0], frame)
+            batch = w.fill(batch)
+            if err := w.flush(batch, bufs); err != nil {
+                return
+            }
+        case <- w.done:
+            for {
+                batch = w.fill(batch[:0])
+                if len(batch) == 0 {
+                    return
+                }
+                if err := w.flush(batch, bufs); err != nil {
+                    return
+                }
+            }
+        }
+    }
+}
+
+func (w *connWriter)fill(batch []writeFrame) []writeFrame {
+    for len(batch) < maxWriteBatch {
+        select {
+        case frame := <- w.queue:
+            batch = append(batch, frame)
+        default:
+            return batch
+        }
+    }
+    return batch
+}
+
+// flush writes batch with one vectored write through bufs and tells the waiting callers whether
+// their frame was written in full.
+func (w *connWriter)flush(batch []writeFrame, bufs net.Buffers) error {
+    bufs = bufs[:0]
+    for _, frame := range batch {
+        bufs = append(bufs, frame.pkg)
+    }
+    // WriteTo consumes the slice it is called on, bufs keeps its backing array for reuse.
+    pending := bufs
+    n, err := pending.WriteTo(w.rw)
+    for i, frame := range batch {
+        n -= int64(len(frame.pkg))
+        if frame.errc != nil {
+            if n >= 0 {
+                frame.errc <- nil
+            } else {
+                frame.errc <- err
+            }
+        }
+        batch[i] = writeFrame{}
+        bufs[i] = nil
+    }
+    if err != nil {
+        w.once.Do(func() {
+            close(w.done)
+        })
+        if w.onError != nil {
+            w.onError(err)
+        }
+    }
+    return err
+}
@@ -0,0 +1,43 @@
+package wrpc_go_test
+
+import (
+    "context"
+    "errors"
+    "net"
+    "testing"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+)
+
+// brokenConn fails every write, as a connection reset by the peer does.
+type brokenConn struct {
+    net.Conn
+}
+
+func (c brokenConn)Write(b []byte) (int, error) {
+    return 0, errors.New("broken pipe")
+}
+
+func TestWriteFailureIsRetried(t *testing.T) {
+    ts1, ts2 := startServer(t), startServer(t)
+    client := newClient(t, ts1,
+        wrpc_go.WithClientOptionAddr(ts1.Addr+";"+ts2.Addr),
+        wrpc_go.WithClientOptionRetryPolicy(&wrpc_go.RetryPolicy{MaxAttempts: 2}),
+        wrpc_go.WithClientOptionDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+            conn, err := dialer(ts1, ts2)(ctx, addr)
+            if err == nil && addr == ts1.Addr {
+                conn = brokenConn{conn}
+            }
+            return conn, err
+        }))
+
+    // a call that could not be written is retried on the other endpoint, idempotent or not.
+    for i := 0; i < 4; i++ {
+        if _, err := invoke(client, context.Background(), "Echo", `"a"`); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if calls := ts2.Calls("Echo"); calls != 4 {
+        t.Fatalf("second endpoint got %d calls, want 4", calls)
+    }
+}
@@ -0,0 +1,113 @@
+package wrpctest
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+)
+
+// Faults change the calls a server handles, a method of "" applies to every method.
+// Streams are not affected.
+type Faults struct {
+    mu      sync.Mutex
+    latency map[string]time.Duration
+    errs    map[string]*injectedError
+    calls   map[string]int
+}
+
+type injectedError struct {
+    err   error
+    times int
+}
+
+func newFaults() *Faults {
+    f := &Faults{}
+    f.Clear()
+    return f
+}
+
+// InjectLatency delays the calls of method by d before the handler runs, the delay ends early
+// when the call is canceled.
+func (f *Faults)InjectLatency(method string, d time.Duration) {
+    f.mu.Lock()
+    f.latency[method] = d
+    f.mu.Unlock()
+}
+
+// InjectError fails the next times calls of method with err without running the handler,
+// every call when times <= 0. Use a uerror to choose the code the client gets.
+func (f *Faults)InjectError(method string, err error, times int) {
+    f.mu.Lock()
+    f.errs[method] = &injectedError{err: err, times: times}
+    f.mu.Unlock()
+}
+
+// Clear removes the injected faults and resets the call counts.
+func (f *Faults)Clear() {
+    f.mu.Lock()
+    f.latency = make(map[string]time.Duration)
+    f.errs = make(map[string]*injectedError)
+    f.calls = make(map[string]int)
+    f.mu.Unlock()
+}
+
+// Calls returns how many calls of method reached the server, failed ones included.
+func (f *Faults)Calls(method string) int {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if method == "" {
+        total := 0
+        for _, n := range f.calls {
+            total += n
+        }
+        return total
+    }
+    return f.calls[method]
+}
+
+func (f *Faults)intercept(ctx context.Context, req *wrpc_go.Request, info *wrpc_go.ServerInfo, handler wrpc_go.ServerHandler) ([]byte, error) {
+    latency, err := f.take(info.Method)
+    if latency > 0 {
+        timer := time.NewTimer(latency)
+        select {
+        case <- timer.C:
+        case <- ctx.Done():
+            timer.Stop()
+            return nil, ctx.Err()
+        }
+    }
+    if err != nil {
+        return nil, err
+    }
+    return handler(ctx, req)
+}
+
+func (f *Faults)take(method string) (time.Duration, error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.calls[method]++
+    latency, ok := f.latency[method]
+    if !ok {
+        latency = f.latency[""]
+    }
+    injected, ok := f.errs[method]
+    if !ok {
+        injected, ok = f.errs[""]
+    }
+    if !ok {
+        return latency, nil
+    }
+    if injected.times > 0 {
+        injected.times--
+        if injected.times == 0 {
+            if _, own := f.errs[method]; own {
+                delete(f.errs, method)
+            } else {
+                delete(f.errs, "")
+            }
+        }
+    }
+    return latency, injected.err
+}
@@ -0,0 +1,137 @@
+package wrpctest
+
+import (
+    "context"
+    "errors"
+    "net"
+    "sync"
+)
+
+var (
+    ErrListenerClosed    = errors.New("wrpctest: listener closed")
+    ErrConnectionRefused = errors.New("wrpctest: connection refused")
+)
+
+// Listener is an in-memory net.Listener, Dial connects to it through a net.Pipe.
+type Listener struct {
+    conns   chan net.Conn
+    mu      sync.Mutex
+    done    chan struct{}
+    closed  bool
+    open    map[net.Conn]struct{}
+    refuse  bool
+}
+
+func NewListener() *Listener {
+    return &Listener{
+        conns: make(chan net.Conn),
+        done: make(chan struct{}),
+        open: make(map[net.Conn]struct{}),
+    }
+}
+
+func (l *Listener)Accept() (net.Conn, error) {
+    done := l.doneChan()
+    select {
+    case conn := <- l.conns:
+        return conn, nil
+    case <- done:
+        return nil, ErrListenerClosed
+    }
+}
+
+func (l *Listener)Close() error {
+    l.mu.Lock()
+    if !l.closed {
+        l.closed = true
+        close(l.done)
+    }
+    l.mu.Unlock()
+    return nil
+}
+
+// reopen lets a closed listener accept again, for a server started after another one stopped.
+func (l *Listener)reopen() {
+    l.mu.Lock()
+    if l.closed {
+        l.closed = false
+        l.done = make(chan struct{})
+    }
+    l.mu.Unlock()
+}
+
+func (l *Listener)doneChan() chan struct{} {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return l.done
+}
+
+func (l *Listener)Addr() net.Addr {
+    return memAddr{}
+}
+
+// Dial has the signature of wrpc_go.Dialer, addr is ignored.
+func (l *Listener)Dial(ctx context.Context, addr string) (net.Conn, error) {
+    l.mu.Lock()
+    refuse := l.refuse
+    l.mu.Unlock()
+    if refuse {
+        return nil, ErrConnectionRefused
+    }
+    client, server := net.Pipe()
+    conn := &trackedConn{Conn: server, l: l}
+    // registered before Accept can return it, so DropConnections never misses an accepted conn
+    l.mu.Lock()
+    l.open[conn] = struct{}{}
+    l.mu.Unlock()
+    done := l.doneChan()
+    select {
+    case l.conns <- conn:
+        return client, nil
+    case <- done:
+        client.Close()
+        conn.Close()
+        return nil, ErrListenerClosed
+    case <- ctx.Done():
+        client.Close()
+        conn.Close()
+        return nil, ctx.Err()
+    }
+}
+
+// DropConnections closes every accepted connection, the clients see them reset.
+func (l *Listener)DropConnections() {
+    l.mu.Lock()
+    conns := make([]net.Conn, 0, len(l.open))
+    for conn := range l.open {
+        conns = append(conns, conn)
+    }
+    l.mu.Unlock()
+    for _, conn := range conns {
+        conn.Close()
+    }
+}
+
+// RefuseConnections makes Dial fail while refuse is true, as if the server was down.
+func (l *Listener)RefuseConnections(refuse bool) {
+    l.mu.Lock()
+    l.refuse = refuse
+    l.mu.Unlock()
+}
+
+type trackedConn struct {
+    net.Conn
+    l    *Listener
+}
+
+func (c *trackedConn)Close() error {
+    c.l.mu.Lock()
+    delete(c.l.open, c)
+    c.l.mu.Unlock()
+    return c.Conn.Close()
+}
+
+type memAddr struct{}
+
+func (memAddr)Network() string { return "memory" }
+func (memAddr)String() string { return "memory" }
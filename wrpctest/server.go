@@ -0,0 +1,113 @@
+// Package wrpctest runs wrpc servers in memory for unit tests, no config file, port or
+// discovery is needed.
+//
+//    ts := wrpctest.NewServer()
+//    defer ts.Close()
+//    ts.Start(pb.NewHelloServer("HelloServer", &impl{}, ts.ServerOptions()...))
+//    client := pb.NewHelloClient("HelloServer", ts.ClientOptions()...)
+package wrpctest
+
+import (
+    "context"
+    "fmt"
+    "sync/atomic"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+)
+
+var serverId int64
+
+// Server is a wrpc server listening on a Listener, with faults that can be injected in its calls.
+type Server struct {
+    // Addr is the endpoint of the server, it only means something to the clients of ClientOptions.
+    Addr     string
+    Listener *Listener
+    *Faults
+    srv      wrpc_go.Server
+}
+
+// NewServer makes the listener and the options of a server, which is then built with the
+// generated constructor and passed to Start. The defaults are used as config unless
+// wrpc_go.LoadConfig was called before.
+func NewServer() *Server {
+    // the defaults always parse, the error can only be ErrConfigLoaded: the config in use is kept.
+    wrpc_go.LoadConfig(nil)
+    return &Server{
+        Addr: fmt.Sprintf("wrpctest-%d", atomic.AddInt64(&serverId, 1)),
+        Listener: NewListener(),
+        Faults: newFaults(),
+    }
+}
+
+// ServerOptions serve on the in-memory listener and inject the faults, they are given to the
+// constructor of the server.
+func (s *Server)ServerOptions(opts ...wrpc_go.ServerOption) []wrpc_go.ServerOption {
+    return append([]wrpc_go.ServerOption{
+        wrpc_go.WithServerOptionListener(s.Listener),
+        wrpc_go.WithServerOptionInterceptors(s.Faults.intercept),
+    }, opts...)
+}
+
+// ClientOptions connect a client to the server with the v2 protocol, they are given to the
+// constructor of the client.
+func (s *Server)ClientOptions(opts ...wrpc_go.ClientOption) []wrpc_go.ClientOption {
+    return append([]wrpc_go.ClientOption{
+        wrpc_go.WithClientOptionAddr(s.Addr),
+        wrpc_go.WithClientOptionDialer(s.Listener.Dial),
+        wrpc_go.WithClientOptionProtocolVersion(wrpc_go.ProtocolV2),
+    }, opts...)
+}
+
+// Start runs srv in the background and returns once it accepts connections.
+func (s *Server)Start(srv wrpc_go.Server) error {
+    s.srv = srv
+    go srv.Start()
+    if waiter, ok := srv.(interface{ WaitReady(ctx context.Context) error }); ok {
+        return waiter.WaitReady(context.Background())
+    }
+    return nil
+}
+
+// Restart stops the running server gracefully, waiting at most timeout for its calls, and
+// starts srv on the same listener, the clients keep their address.
+func (s *Server)Restart(srv wrpc_go.Server, timeout time.Duration) error {
+    if s.srv != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), timeout)
+        err := s.srv.Stop(ctx)
+        cancel()
+        if err != nil {
+            return err
+        }
+    }
+    s.Listener.reopen()
+    return s.Start(srv)
+}
+
+// NewClient returns a client of the server for Invoke, Go and NewStream.
+func (s *Server)NewClient(name string, opts ...wrpc_go.ClientOption) *wrpc_go.Client {
+    return wrpc_go.NewClient(name, s.ClientOptions(opts...)...)
+}
+
+// DropConnections closes every connection to the server, calls in flight fail.
+func (s *Server)DropConnections() {
+    s.Listener.DropConnections()
+}
+
+// RefuseConnections makes new connections to the server fail while refuse is true.
+func (s *Server)RefuseConnections(refuse bool) {
+    s.Listener.RefuseConnections(refuse)
+}
+
+// Close stops the server, the calls in flight are canceled.
+func (s *Server)Close() error {
+    var err error
+    if s.srv != nil {
+        ctx, cancel := context.WithCancel(context.Background())
+        cancel()
+        err = s.srv.Stop(ctx)
+    }
+    s.Listener.Close()
+    s.Listener.DropConnections()
+    return err
+}
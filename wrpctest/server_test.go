@@ -0,0 +1,108 @@
+package wrpctest_test
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    wrpc_go "github.com/wukong-cloud/wrpc-go"
+    "github.com/wukong-cloud/wrpc-go/util/uerror"
+    "github.com/wukong-cloud/wrpc-go/wrpctest"
+)
+
+func echo(ctx context.Context, impl interface{}, req *wrpc_go.Request, enc wrpc_go.Encoder) ([]byte, error) {
+    return req.Body, nil
+}
+
+func start(t *testing.T) (*wrpctest.Server, *wrpc_go.Client) {
+    ts := wrpctest.NewServer()
+    if err := ts.Start(wrpc_go.NewRPCServer("EchoServer", nil, echo, ts.ServerOptions()...)); err != nil {
+        t.Fatal(err)
+    }
+    client := ts.NewClient("EchoServer")
+    t.Cleanup(func() {
+        client.Close()
+        ts.Close()
+    })
+    return ts, client
+}
+
+func call(client *wrpc_go.Client, ctx context.Context) error {
+    _, err := client.Invoke(ctx, "json", "", "Echo", []byte(`"a"`))
+    return err
+}
+
+func TestInjectError(t *testing.T) {
+    ts, client := start(t)
+    ts.InjectError("Echo", uerror.NewError(503, "down"), 2)
+    for i := 0; i < 2; i++ {
+        if err := call(client, context.Background()); uerror.ParseError(err).Code != 503 {
+            t.Fatalf("call %d: got %v, want the injected error", i, err)
+        }
+    }
+    if err := call(client, context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    if calls := ts.Calls("Echo"); calls != 3 {
+        t.Fatalf("got %d calls, want 3", calls)
+    }
+}
+
+func TestInjectLatency(t *testing.T) {
+    ts, client := start(t)
+    ts.InjectLatency("", time.Second)
+    ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+    defer cancel()
+    if err := call(client, ctx); err == nil {
+        t.Fatal("expected the call to time out")
+    }
+    ts.Clear()
+    if err := call(client, context.Background()); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestDropAndRefuseConnections(t *testing.T) {
+    ts, client := start(t)
+    if err := call(client, context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    ts.RefuseConnections(true)
+    ts.DropConnections()
+    time.Sleep(20 * time.Millisecond)
+    if err := call(client, context.Background()); err == nil {
+        t.Fatal("expected the call to fail while connections are refused")
+    }
+    ts.RefuseConnections(false)
+    if err := call(client, context.Background()); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestDropAcceptedConnection(t *testing.T) {
+    l := wrpctest.NewListener()
+    defer l.Close()
+    accepted := make(chan struct{})
+    go func() {
+        if _, err := l.Accept(); err == nil {
+            // dropped right after Accept returns, before Dial has returned
+            l.DropConnections()
+        }
+        close(accepted)
+    }()
+    client, err := l.Dial(context.Background(), "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer client.Close()
+    <-accepted
+    client.SetReadDeadline(time.Now().Add(time.Second))
+    if _, err := client.Read(make([]byte, 1)); err == nil || isTimeout(err) {
+        t.Fatalf("expected the connection to be dropped, got %v", err)
+    }
+}
+
+func isTimeout(err error) bool {
+    ne, ok := err.(interface{ Timeout() bool })
+    return ok && ne.Timeout()
+}